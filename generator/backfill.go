@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/grafana/explore-logs/generator/log"
+	"github.com/grafana/loki/pkg/push"
+	"github.com/prometheus/common/model"
+)
+
+// Backfill runs the generators in virtual time over [from, to).
+//
+// Virtual time advances in windows: lines are buffered until every generator goroutine slept past the end
+// of the current window, then written to their sink ordered by timestamp, as fast as the sink accepts them.
+// Lines are written without the backfill lock held, the generators run the next window meanwhile.
+// Once to is reached, Done is closed and the clocks switch to real time.
+type Backfill struct {
+	from, to time.Time
+	window   time.Duration
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	started bool
+	done    chan struct{}
+	// end is the end of the current window, clocks sleeping past it wait for the window to be flushed.
+	end time.Time
+	// blocked is the number of clocks waiting for the current window to be flushed.
+	blocked int
+	clocks  map[*backfillClock]struct{}
+	entries []backfillEntry
+	// flushed are the sorted lines of the flushed windows, written by unlock.
+	flushed []backfillEntry
+	// final is set once the last window was flushed, unlock closes drained after writing it.
+	final bool
+
+	// sending is held while writing flushed lines, so that windows are written in order.
+	sending sync.Mutex
+	// drained is closed once the lines of the last window were written, before the first live line.
+	drained chan struct{}
+}
+
+type backfillEntry struct {
+	logger    log.Logger
+	labels    model.LabelSet
+	timestamp time.Time
+	message   string
+	metadata  push.LabelsAdapter
+}
+
+// NewBackfill creates a backfill over [from, to), flushing lines every window of virtual time.
+// Generators started with its clocks wait until Start is called.
+func NewBackfill(ctx context.Context, from, to time.Time, window time.Duration) *Backfill {
+	b := &Backfill{
+		from:    from,
+		to:      to,
+		window:  window,
+		done:    make(chan struct{}),
+		drained: make(chan struct{}),
+		clocks:  map[*backfillClock]struct{}{},
+	}
+	b.cond = sync.NewCond(&b.mu)
+	b.end = b.windowEnd(from)
+	context.AfterFunc(ctx, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.cond.Broadcast()
+	})
+	return b
+}
+
+// Start lets the generators run, once all of them got their clock.
+func (b *Backfill) Start() {
+	b.mu.Lock()
+	defer b.unlock()
+	b.started = true
+	b.maybeAdvance()
+}
+
+// Done is closed once the backfill reached its end time.
+func (b *Backfill) Done() <-chan struct{} {
+	return b.done
+}
+
+// Flush writes the lines of the current window, on shutdown once the generators stopped.
+func (b *Backfill) Flush() {
+	b.mu.Lock()
+	defer b.unlock()
+	b.flush()
+}
+
+func (b *Backfill) finished() bool {
+	select {
+	case <-b.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Logger buffers the lines of the current window until they can be written to next in timestamp order.
+func (b *Backfill) Logger(next log.Logger) log.Logger {
+	return log.LoggerFunc(func(labels model.LabelSet, timestamp time.Time, message string, metadata push.LabelsAdapter) error {
+		b.mu.Lock()
+		if b.finished() {
+			b.mu.Unlock()
+			<-b.drained
+			return next.HandleWithMetadata(labels, timestamp, message, metadata)
+		}
+		defer b.mu.Unlock()
+		b.entries = append(b.entries, backfillEntry{
+			logger:    next,
+			labels:    labels,
+			timestamp: timestamp,
+			message:   message,
+			metadata:  metadata,
+		})
+		return nil
+	})
+}
+
+// Clocks is the ClockSource of the backfill, every clock starts at from.
+func (b *Backfill) Clocks() (Clock, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := &backfillClock{b: b, now: b.from}
+	b.clocks[c] = struct{}{}
+	return c, func() {
+		b.mu.Lock()
+		defer b.unlock()
+		delete(b.clocks, c)
+		b.maybeAdvance()
+	}
+}
+
+// sleep advances the clock by d, and blocks it until its time is within the current window.
+func (b *Backfill) sleep(ctx context.Context, c *backfillClock, d time.Duration) {
+	b.mu.Lock()
+	c.now = c.now.Add(d)
+	if !c.now.Before(b.end) {
+		b.blocked++
+	}
+	c.waiting = true
+	b.maybeAdvance()
+	if len(b.flushed) > 0 || b.final {
+		// Write the window this clock flushed before waiting for the next one.
+		b.unlock()
+		b.mu.Lock()
+	}
+	defer b.mu.Unlock()
+	for !c.now.Before(b.end) && !b.finished() && ctx.Err() == nil {
+		b.cond.Wait()
+	}
+	c.waiting = false
+	if !c.now.Before(b.end) {
+		b.blocked--
+	}
+}
+
+// maybeAdvance flushes the current window and moves to the next one once every clock is blocked.
+func (b *Backfill) maybeAdvance() {
+	if !b.started || b.finished() || b.blocked < len(b.clocks) {
+		return
+	}
+	b.flush()
+
+	next := b.to
+	for c := range b.clocks {
+		if c.now.Before(next) {
+			next = c.now
+		}
+	}
+	if !next.Before(b.to) {
+		b.final = true
+		close(b.done)
+		b.cond.Broadcast()
+		return
+	}
+
+	b.end = b.windowEnd(next)
+	b.blocked = 0
+	for c := range b.clocks {
+		if c.waiting && !c.now.Before(b.end) {
+			b.blocked++
+		}
+	}
+	b.cond.Broadcast()
+}
+
+// windowEnd returns the end of the window t belongs to.
+func (b *Backfill) windowEnd(t time.Time) time.Time {
+	end := b.from.Add((t.Sub(b.from)/b.window + 1) * b.window)
+	if end.After(b.to) {
+		return b.to
+	}
+	return end
+}
+
+// flush sorts the lines of the current window and hands them over to unlock.
+func (b *Backfill) flush() {
+	sort.SliceStable(b.entries, func(i, j int) bool {
+		return b.entries[i].timestamp.Before(b.entries[j].timestamp)
+	})
+	b.flushed = append(b.flushed, b.entries...)
+	b.entries = nil
+}
+
+// unlock releases the backfill lock, then writes the flushed lines to their sink.
+func (b *Backfill) unlock() {
+	flushed, final := b.flushed, b.final
+	if len(flushed) == 0 && !final {
+		b.mu.Unlock()
+		return
+	}
+	b.flushed, b.final = nil, false
+	// The next flush waits for this one with the backfill lock held, keeping the windows in order.
+	b.sending.Lock()
+	b.mu.Unlock()
+	defer b.sending.Unlock()
+	for _, e := range flushed {
+		if err := e.logger.HandleWithMetadata(e.labels, e.timestamp, e.message, e.metadata); err != nil {
+			fmt.Fprintf(os.Stderr, "Error logging message: %s\n", err)
+		}
+	}
+	if final {
+		close(b.drained)
+	}
+}
+
+type backfillClock struct {
+	b *Backfill
+	// now is only written by Backfill.sleep, with the backfill lock held.
+	now     time.Time
+	waiting bool
+}
+
+func (c *backfillClock) Now() time.Time {
+	if c.b.finished() {
+		return time.Now()
+	}
+	return c.now
+}
+
+func (c *backfillClock) Sleep(ctx context.Context, d time.Duration) {
+	if c.b.finished() {
+		realClock{}.Sleep(ctx, d)
+		return
+	}
+	c.b.sleep(ctx, c, d)
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafana/explore-logs/generator/log"
+	"github.com/grafana/loki/pkg/push"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recorder is a sink keeping the lines written to it.
+type recorder struct {
+	mu    sync.Mutex
+	lines []backfillEntry
+}
+
+func (r *recorder) logger() log.Logger {
+	return log.LoggerFunc(func(labels model.LabelSet, timestamp time.Time, message string, _ push.LabelsAdapter) error {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.lines = append(r.lines, backfillEntry{labels: labels, timestamp: timestamp, message: message})
+		return nil
+	})
+}
+
+func (r *recorder) len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.lines)
+}
+
+func TestBackfillOrdersStreams(t *testing.T) {
+	from := time.Date(2024, 10, 1, 14, 0, 0, 0, time.UTC)
+	to := from.Add(10 * time.Minute)
+	b := NewBackfill(context.Background(), from, to, time.Minute)
+	var sink recorder
+	logger := b.Logger(sink.logger())
+
+	var wg sync.WaitGroup
+	for _, interval := range []time.Duration{7 * time.Second, 13 * time.Second, 29 * time.Second} {
+		clock, release := b.Clocks()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer release()
+			labels := model.LabelSet{"service_name": model.LabelValue(interval.String())}
+			for now := clock.Now(); now.Before(to); now = clock.Now() {
+				assert.NoError(t, logger.Handle(labels, now, "line"))
+				clock.Sleep(context.Background(), interval)
+			}
+		}()
+	}
+	b.Start()
+	wg.Wait()
+
+	select {
+	case <-b.Done():
+	default:
+		t.Fatal("the backfill is not done at its end time")
+	}
+	counts := map[model.LabelValue]int{}
+	for i, line := range sink.lines {
+		counts[line.labels["service_name"]]++
+		assert.False(t, line.timestamp.Before(from))
+		assert.True(t, line.timestamp.Before(to))
+		if i > 0 {
+			assert.False(t, line.timestamp.Before(sink.lines[i-1].timestamp), "lines are written in timestamp order")
+		}
+	}
+	// A line every interval from the start, up to the end.
+	assert.Equal(t, map[model.LabelValue]int{"7s": 86, "13s": 47, "29s": 21}, counts)
+}
+
+func TestBackfillWindows(t *testing.T) {
+	ctx := context.Background()
+	from := time.Date(2024, 10, 1, 14, 0, 0, 0, time.UTC)
+	b := NewBackfill(ctx, from, from.Add(3*time.Minute), time.Minute)
+	var sink recorder
+	logger := b.Logger(sink.logger())
+	clock, release := b.Clocks()
+	defer release()
+	b.Start()
+
+	require.NoError(t, logger.Handle(nil, clock.Now(), "first"))
+	clock.Sleep(ctx, 30*time.Second)
+	assert.Equal(t, from.Add(30*time.Second), clock.Now())
+	assert.Zero(t, sink.len(), "lines are buffered until the end of their window")
+
+	require.NoError(t, logger.Handle(nil, clock.Now(), "second"))
+	clock.Sleep(ctx, 40*time.Second)
+	assert.Equal(t, 2, sink.len(), "the first window is written once the clock slept past it")
+
+	require.NoError(t, logger.Handle(nil, clock.Now(), "third"))
+	clock.Sleep(ctx, 2*time.Minute)
+	select {
+	case <-b.Done():
+	default:
+		t.Fatal("the backfill is not done at its end time")
+	}
+	assert.Equal(t, 3, sink.len())
+
+	// Live lines are written right away, in real time.
+	assert.WithinDuration(t, time.Now(), clock.Now(), time.Minute)
+	require.NoError(t, logger.Handle(nil, clock.Now(), "live"))
+	assert.Equal(t, 4, sink.len())
+	start := time.Now()
+	clock.Sleep(ctx, 10*time.Millisecond)
+	assert.GreaterOrEqual(t, time.Since(start), 10*time.Millisecond)
+}
+
+func TestBackfillWritesWithoutLock(t *testing.T) {
+	ctx := context.Background()
+	from := time.Date(2024, 10, 1, 14, 0, 0, 0, time.UTC)
+	b := NewBackfill(ctx, from, from.Add(time.Hour), time.Minute)
+	unblock := make(chan struct{})
+	slow := log.LoggerFunc(func(model.LabelSet, time.Time, string, push.LabelsAdapter) error {
+		<-unblock
+		return nil
+	})
+	logger := b.Logger(slow)
+	first, releaseFirst := b.Clocks()
+	second, releaseSecond := b.Clocks()
+	b.Start()
+
+	logged := make(chan struct{})
+	go func() {
+		defer releaseSecond()
+		second.Sleep(ctx, 65*time.Second)
+		// The first window is being written to the slow sink meanwhile.
+		assert.NoError(t, logger.Handle(nil, second.Now(), "next window"))
+		close(logged)
+	}()
+	require.Eventually(t, func() bool {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		return b.blocked == 1
+	}, time.Second, time.Millisecond)
+
+	go func() {
+		defer releaseFirst()
+		assert.NoError(t, logger.Handle(nil, first.Now(), "first window"))
+		first.Sleep(ctx, 70*time.Second)
+	}()
+	select {
+	case <-logged:
+	case <-time.After(5 * time.Second):
+		t.Fatal("a slow sink blocks the generators")
+	}
+	close(unblock)
+}
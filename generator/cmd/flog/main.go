@@ -0,0 +1,26 @@
+// Command flog is a drop-in replacement for the upstream flog fake log generator, see flog -h.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/grafana/explore-logs/generator/flog"
+)
+
+func main() {
+	opts := flog.ParseOptions()
+
+	// Stop looping on SIGINT or SIGTERM, so that the last file is closed and compressed.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if err := flog.Generate(ctx, gofakeit.New(0), opts); err != nil {
+		fmt.Fprintln(os.Stderr, "Error while generating logs:", err)
+		os.Exit(1)
+	}
+}
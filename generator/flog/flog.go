@@ -0,0 +1,92 @@
+package flog
+
+import (
+	"context"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v7"
+)
+
+// Generate writes the logs described by option: Number lines, or Bytes of lines when set, or lines until ctx is
+// done with Forever. Sleep is the gap between the timestamps of two lines, Delay the real time waited before each.
+func Generate(ctx context.Context, f *gofakeit.Faker, option *Option) error {
+	writer, err := NewWriter(option.Type, option.Output, RotateOptions{
+		SplitBy:    option.SplitBy,
+		SplitBytes: option.Bytes > 0,
+		Overwrite:  option.Overwrite,
+	})
+	if err != nil {
+		return err
+	}
+
+	var (
+		created = time.Now()
+		lines   int
+		bytes   int
+	)
+	done := func() bool {
+		switch {
+		case ctx.Err() != nil:
+			return true
+		case option.Forever:
+			return false
+		case option.Bytes > 0:
+			return bytes >= option.Bytes
+		}
+		return lines >= option.Number
+	}
+	for !done() {
+		if option.Delay > 0 {
+			select {
+			case <-ctx.Done():
+				continue
+			case <-time.After(option.Delay):
+			}
+		}
+		n, err := writer.Write([]byte(NewLog(f, option.Format, created) + "\n"))
+		if err != nil {
+			writer.Close()
+			return err
+		}
+		lines++
+		bytes += n
+		created = created.Add(option.Sleep)
+	}
+	return writer.Close()
+}
+
+// NewLog creates a log line of the given format
+func NewLog(f *gofakeit.Faker, format string, t time.Time) string {
+	switch format {
+	case "apache_combined":
+		return NewApacheCombinedLog(f, t, RandResourceURI(f), f.HTTPStatusCode())
+	case "apache_error":
+		return NewApacheErrorLog(f, t)
+	case "rfc3164":
+		return NewRFC3164Log(f, t)
+	case "rfc5424":
+		return NewRFC5424Log(f, t)
+	case "common_log":
+		return NewCommonLogFormat(f, t, RandResourceURI(f), f.HTTPStatusCode())
+	case "json":
+		return NewJSONLogFormat(f, t, RandResourceURI(f), f.HTTPStatusCode())
+	case "nginx_error":
+		return NewNginxErrorLog(f, t, randLevel(f), f.Number(1, 64))
+	case "postgres":
+		return NewPostgresLog(f, t, randLevel(f), f.Number(1, 32768))
+	case "mysql_error":
+		return NewMySQLErrorLog(f, t, randLevel(f))
+	case "mysql_slow":
+		return NewMySQLSlowLog(f, t)
+	case "redis":
+		return NewRedisLog(f, t, randLevel(f), f.Number(1, 32768))
+	case "kafka":
+		return NewKafkaLog(f, t, randLevel(f), f.Number(1, 3))
+	case "windows_event":
+		return NewWindowsEvent(f, t, randLevel(f), "WIN-DESKTOP01", f.Number(1, 1000000)).JSON()
+	case "windows_event_xml":
+		return NewWindowsEvent(f, t, randLevel(f), "WIN-DESKTOP01", f.Number(1, 1000000)).XML()
+	default:
+		return NewApacheCommonLog(f, t, RandResourceURI(f), f.HTTPStatusCode())
+	}
+}
@@ -0,0 +1,81 @@
+package flog
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func generate(t *testing.T, option *Option) []string {
+	option.Type = "log"
+	option.Output = filepath.Join(t.TempDir(), "generated.log")
+	require.NoError(t, Generate(context.Background(), gofakeit.New(1), option))
+
+	f, err := os.Open(option.Output)
+	require.NoError(t, err)
+	defer f.Close()
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines
+}
+
+func TestGenerateNumber(t *testing.T) {
+	option := defaultOptions()
+	option.Number = 5
+	option.Format = "rfc5424"
+	option.Sleep = time.Hour
+	lines := generate(t, option)
+	require.Len(t, lines, 5)
+
+	first, err := time.Parse(RFC5424, strings.Fields(lines[0])[1])
+	require.NoError(t, err)
+	last, err := time.Parse(RFC5424, strings.Fields(lines[4])[1])
+	require.NoError(t, err)
+	assert.Equal(t, 4*time.Hour, last.Sub(first), "sleep advances the timestamps")
+}
+
+func TestGenerateBytes(t *testing.T) {
+	option := defaultOptions()
+	option.Bytes = 1000
+	lines := generate(t, option)
+
+	size := 0
+	for _, line := range lines {
+		size += len(line) + 1
+	}
+	assert.GreaterOrEqual(t, size, 1000)
+	assert.Less(t, size-len(lines[len(lines)-1])-1, 1000, "no line is written once the size is reached")
+}
+
+func TestGenerateForever(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	option := defaultOptions()
+	option.Forever = true
+	option.Delay = time.Millisecond
+	option.Output = filepath.Join(t.TempDir(), "generated.log")
+	option.Type = "log"
+	require.NoError(t, Generate(ctx, gofakeit.New(1), option))
+
+	data, err := os.ReadFile(option.Output)
+	require.NoError(t, err)
+	assert.Greater(t, strings.Count(string(data), "\n"), option.Number/100, "lines are written until the context is done")
+}
+
+func TestNewLog(t *testing.T) {
+	f := gofakeit.New(1)
+	for _, format := range validFormats {
+		assert.NotEmpty(t, NewLog(f, format, time.Now()), format)
+	}
+}
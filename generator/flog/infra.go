@@ -0,0 +1,437 @@
+package flog
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v7"
+)
+
+const (
+	// NginxErrorLog : {datetime} [{severity}] {pid}#{tid}: {message}
+	NginxErrorLog = "%s [%s] %d#%d: %s"
+	// PostgresLog : {timestamp} [{pid}] {severity}:  {message}, the default log_line_prefix.
+	PostgresLog = "%s [%d] %s:  %s"
+	// MySQLErrorLog : {timestamp} {thread-id} [{priority}] [MY-{error-code}] [{subsystem}] {message}
+	MySQLErrorLog = "%s %d [%s] [MY-%06d] [%s] %s"
+	// MySQLSlowLog : a slow query log entry, from its time to its statement.
+	MySQLSlowLog = "# Time: %s\n# User@Host: %s[%s] @  [%s]  Id: %5d\n# Query_time: %.6f  Lock_time: %.6f Rows_sent: %d  Rows_examined: %d\nSET timestamp=%d;\n%s;"
+	// RedisLog : {pid}:{role} {timestamp} {level} {message}
+	RedisLog = "%d:%s %s %s %s"
+	// KafkaLog : [{timestamp}] {level} {message} ({logger})
+	KafkaLog = "[%s] %s %s (%s)"
+)
+
+// Levels of the infrastructure logs, named like the levels of the generator.
+const (
+	levelDebug = "debug"
+	levelInfo  = "info"
+	levelWarn  = "warn"
+	levelError = "error"
+)
+
+// randLevel picks the level of a line of the flog command, mostly info.
+func randLevel(f *gofakeit.Faker) string {
+	return f.RandomString([]string{levelDebug, levelInfo, levelInfo, levelInfo, levelInfo, levelInfo, levelWarn, levelError})
+}
+
+// message is a message of a component, drawn from f.
+type message func(f *gofakeit.Faker) string
+
+// pickMessage picks a message for level, or an info one if the component has none at that level.
+func pickMessage(f *gofakeit.Faker, messages map[string][]message, level string) string {
+	candidates, ok := messages[level]
+	if !ok {
+		candidates = messages[levelInfo]
+	}
+	return candidates[f.IntN(len(candidates))](f)
+}
+
+var (
+	hosts  = []string{"shop.example.com", "api.example.com", "static.example.com"}
+	tables = []string{"orders", "order_items", "carts", "customers", "payments", "inventory"}
+	topics = []string{"orders", "payments", "cart-events", "inventory-updates"}
+)
+
+// RandSQL returns a random statement of an e-commerce application.
+func RandSQL(f *gofakeit.Faker) string {
+	table := tables[f.IntN(len(tables))]
+	switch f.IntN(4) {
+	case 0:
+		return fmt.Sprintf("SELECT * FROM %s WHERE customer_id = %d ORDER BY created_at DESC LIMIT %d", table, f.Number(1, 100000), f.Number(10, 100))
+	case 1:
+		return fmt.Sprintf("UPDATE %s SET status = '%s', updated_at = now() WHERE id = %d", table, f.RandomString([]string{"paid", "shipped", "cancelled"}), f.Number(1, 1000000))
+	case 2:
+		return fmt.Sprintf("INSERT INTO %s (customer_id, total, status) VALUES (%d, %.2f, 'pending')", table, f.Number(1, 100000), f.Price(5, 500))
+	default:
+		return fmt.Sprintf("SELECT count(*) FROM %s o JOIN customers c ON c.id = o.customer_id WHERE o.created_at > now() - interval '%d days'", table, f.Number(1, 90))
+	}
+}
+
+func nginxRequest(f *gofakeit.Faker) string {
+	host := hosts[f.IntN(len(hosts))]
+	return fmt.Sprintf(`client: %s, server: %s, request: "%s %s HTTP/1.1"`, FakeIP(f), host, f.HTTPMethod(), RandResourceURI(f))
+}
+
+var nginxMessages = map[string][]message{
+	levelDebug: {
+		func(f *gofakeit.Faker) string { return fmt.Sprintf("*%d http keepalive handler", f.Number(1, 100000)) },
+		func(f *gofakeit.Faker) string {
+			return fmt.Sprintf("*%d http upstream request: \"%s\"", f.Number(1, 100000), RandResourceURI(f))
+		},
+	},
+	levelInfo: {
+		func(f *gofakeit.Faker) string {
+			return fmt.Sprintf("*%d client %s closed keepalive connection", f.Number(1, 100000), FakeIP(f))
+		},
+		func(f *gofakeit.Faker) string {
+			return fmt.Sprintf("*%d client timed out (110: Connection timed out) while waiting for request, client: %s, server: 0.0.0.0:80", f.Number(1, 100000), FakeIP(f))
+		},
+	},
+	levelWarn: {
+		func(f *gofakeit.Faker) string {
+			return fmt.Sprintf("*%d an upstream response is buffered to a temporary file /var/cache/nginx/proxy_temp/%d/%02d/%010d while reading upstream, %s", f.Number(1, 100000), f.Number(0, 9), f.Number(0, 99), f.Number(1, 1000000), nginxRequest(f))
+		},
+		func(f *gofakeit.Faker) string {
+			return fmt.Sprintf("*%d client intended to send too large body: %d bytes, %s", f.Number(1, 100000), f.Number(1048577, 50000000), nginxRequest(f))
+		},
+	},
+	levelError: {
+		func(f *gofakeit.Faker) string {
+			return fmt.Sprintf(`*%d connect() failed (111: Connection refused) while connecting to upstream, %s, upstream: "http://10.0.%d.%d:8080"`, f.Number(1, 100000), nginxRequest(f), f.Number(0, 9), f.Number(1, 254))
+		},
+		func(f *gofakeit.Faker) string {
+			return fmt.Sprintf(`*%d upstream timed out (110: Connection timed out) while reading response header from upstream, %s, upstream: "http://10.0.%d.%d:8080"`, f.Number(1, 100000), nginxRequest(f), f.Number(0, 9), f.Number(1, 254))
+		},
+		func(f *gofakeit.Faker) string {
+			return fmt.Sprintf(`*%d open() "/usr/share/nginx/html%s" failed (2: No such file or directory), %s`, f.Number(1, 100000), RandResourceURI(f), nginxRequest(f))
+		},
+	},
+}
+
+// NewNginxErrorLog creates a log string with nginx error.log format, written by the worker pid
+func NewNginxErrorLog(f *gofakeit.Faker, t time.Time, level string, pid int) string {
+	severity := level
+	if level == levelError && f.IntN(10) == 0 {
+		severity = "crit"
+	}
+	return fmt.Sprintf(
+		NginxErrorLog,
+		t.UTC().Format(NginxError),
+		severity,
+		pid,
+		0,
+		pickMessage(f, nginxMessages, level),
+	)
+}
+
+// postgresPrefix is the log_line_prefix of the continuation lines of an entry.
+func postgresPrefix(t time.Time, pid int) string {
+	return fmt.Sprintf("%s [%d] ", t.UTC().Format(Postgres), pid)
+}
+
+var postgresSeverities = map[string]string{levelDebug: "DEBUG1", levelInfo: "LOG", levelWarn: "WARNING", levelError: "ERROR"}
+
+// postgresMessages continue on lines of their own with the prefix of the entry, see postgresPrefix.
+var postgresMessages = map[string][]func(f *gofakeit.Faker, prefix string, pid int) string{
+	levelDebug: {
+		func(f *gofakeit.Faker, _ string, _ int) string { return `autovacuum: processing database "shop"` },
+		func(f *gofakeit.Faker, _ string, _ int) string {
+			return fmt.Sprintf(`"%s": scanned %d of %d pages, containing %d live rows and %d dead rows`, tables[f.IntN(len(tables))], f.Number(1, 500), f.Number(500, 5000), f.Number(1000, 100000), f.Number(0, 1000))
+		},
+	},
+	levelInfo: {
+		func(f *gofakeit.Faker, _ string, _ int) string {
+			return fmt.Sprintf("connection received: host=%s port=%d", FakeIP(f), f.Number(30000, 65535))
+		},
+		func(f *gofakeit.Faker, _ string, _ int) string {
+			return "connection authorized: user=app database=shop application_name=orders"
+		},
+		func(f *gofakeit.Faker, _ string, _ int) string {
+			buffers := f.Number(10, 20000)
+			return fmt.Sprintf("checkpoint complete: wrote %d buffers (%.1f%%); 0 WAL file(s) added, 0 removed, %d recycled; write=%.3f s, sync=%.3f s, total=%.3f s", buffers, float64(buffers)/163.84, f.Number(0, 5), f.Float64Range(0.1, 270), f.Float64Range(0, 2), f.Float64Range(0.1, 272))
+		},
+		// Slow statements, above log_min_duration_statement.
+		func(f *gofakeit.Faker, _ string, _ int) string {
+			return fmt.Sprintf("duration: %.3f ms  statement: %s", f.Float64Range(500, 30000), RandSQL(f))
+		},
+		func(f *gofakeit.Faker, _ string, _ int) string {
+			return fmt.Sprintf("duration: %.3f ms  execute <unnamed>: %s", f.Float64Range(500, 30000), RandSQL(f))
+		},
+	},
+	levelWarn: {
+		func(f *gofakeit.Faker, _ string, _ int) string { return "there is no transaction in progress" },
+		func(f *gofakeit.Faker, _ string, _ int) string {
+			return fmt.Sprintf(`skipping vacuum of "%s" --- lock not available`, tables[f.IntN(len(tables))])
+		},
+	},
+	levelError: {
+		func(f *gofakeit.Faker, prefix string, _ int) string {
+			id := f.Number(1, 1000000)
+			return fmt.Sprintf("duplicate key value violates unique constraint \"orders_pkey\"\n%sDETAIL:  Key (id)=(%d) already exists.\n%sSTATEMENT:  INSERT INTO orders (id, customer_id, total) VALUES (%d, %d, %.2f)", prefix, id, prefix, id, f.Number(1, 100000), f.Price(5, 500))
+		},
+		func(f *gofakeit.Faker, prefix string, _ int) string {
+			return fmt.Sprintf("canceling statement due to statement timeout\n%sSTATEMENT:  %s", prefix, RandSQL(f))
+		},
+		// Deadlocks name both backends and their statements.
+		func(f *gofakeit.Faker, prefix string, pid int) string {
+			other, tx, otherTx, table := pid+f.Number(1, 64), f.Number(100000, 999999), f.Number(100000, 999999), tables[f.IntN(len(tables))]
+			statement := fmt.Sprintf("UPDATE %s SET status = 'paid' WHERE id = %d", table, f.Number(1, 1000000))
+			return fmt.Sprintf("deadlock detected\n"+
+				"%sDETAIL:  Process %d waits for ShareLock on transaction %d; blocked by process %d.\n"+
+				"\tProcess %d waits for ShareLock on transaction %d; blocked by process %d.\n"+
+				"\tProcess %d: %s\n"+
+				"\tProcess %d: UPDATE %s SET status = 'shipped' WHERE id = %d\n"+
+				"%sHINT:  See server log for query details.\n"+
+				"%sCONTEXT:  while updating tuple (%d,%d) in relation \"%s\"\n"+
+				"%sSTATEMENT:  %s",
+				prefix, pid, otherTx, other,
+				other, tx, pid,
+				pid, statement,
+				other, table, f.Number(1, 1000000),
+				prefix,
+				prefix, f.Number(0, 5000), f.Number(1, 60), table,
+				prefix, statement)
+		},
+	},
+}
+
+// NewPostgresLog creates a log string with PostgreSQL format. pid is the postmaster, the backends writing the lines
+// are its children. Errors, slow statements and deadlocks span several lines, each with the prefix of the entry.
+func NewPostgresLog(f *gofakeit.Faker, t time.Time, level string, pid int) string {
+	backend := pid + f.Number(1, 64)
+	severity := postgresSeverities[level]
+	if level == levelError && f.IntN(10) == 0 {
+		return fmt.Sprintf(PostgresLog, t.UTC().Format(Postgres), backend, "FATAL", `password authentication failed for user "app"`)
+	}
+	messages, ok := postgresMessages[level]
+	if !ok {
+		messages, severity = postgresMessages[levelInfo], postgresSeverities[levelInfo]
+	}
+	msg := messages[f.IntN(len(messages))](f, postgresPrefix(t, backend), backend)
+	return fmt.Sprintf(PostgresLog, t.UTC().Format(Postgres), backend, severity, msg)
+}
+
+// mysqlMessage is a message of the MySQL error log, with its code and subsystem.
+type mysqlMessage struct {
+	code      int
+	subsystem string
+	message   message
+}
+
+var mysqlPriorities = map[string]string{levelDebug: "Note", levelInfo: "Note", levelWarn: "Warning", levelError: "ERROR"}
+
+var mysqlMessages = map[string][]mysqlMessage{
+	levelInfo: {
+		{10914, "Server", func(f *gofakeit.Faker) string {
+			return fmt.Sprintf("Aborted connection %d to db: 'shop' user: 'app' host: '%s' (Got an error reading communication packets).", f.Number(1, 100000), FakeIP(f))
+		}},
+		{10051, "Server", func(f *gofakeit.Faker) string {
+			return fmt.Sprintf("Event Scheduler: scheduler thread started with id %d", f.Number(1, 100))
+		}},
+		{13487, "InnoDB", func(f *gofakeit.Faker) string {
+			return fmt.Sprintf("Resizing redo log from %dM to %dM, LSN=%d.", f.Number(1, 8)*48, f.Number(1, 8)*48, f.Number(10000000, 900000000))
+		}},
+	},
+	levelWarn: {
+		{10055, "Server", func(f *gofakeit.Faker) string {
+			return fmt.Sprintf("IP address '%s' could not be resolved: Name or service not known", FakeIP(f))
+		}},
+		{13360, "Server", func(f *gofakeit.Faker) string {
+			return "Plugin mysql_native_password reported: ''mysql_native_password' is deprecated and will be removed in a future release. Please use caching_sha2_password instead'"
+		}},
+		{12486, "InnoDB", func(f *gofakeit.Faker) string {
+			return fmt.Sprintf("Difficult to find free blocks in the buffer pool (%d search iterations)! %d failed attempts to flush a page!", f.Number(21, 500), f.Number(0, 10))
+		}},
+	},
+	levelError: {
+		{10584, "Repl", func(f *gofakeit.Faker) string {
+			id := f.Number(1, 1000000)
+			return fmt.Sprintf("Replica SQL for channel '': Worker 1 failed executing transaction 'ANONYMOUS' at source log binlog.%06d, end_log_pos %d; Error 'Duplicate entry '%d' for key 'orders.PRIMARY'' on query. Default database: 'shop'. Query: 'INSERT INTO orders (id) VALUES (%d)', Error_code: MY-001062", f.Number(1, 300), f.Number(1000, 100000000), id, id)
+		}},
+		{12574, "InnoDB", func(f *gofakeit.Faker) string { return "Unable to lock ./ibdata1 error: 11" }},
+		{10283, "Server", func(f *gofakeit.Faker) string {
+			return fmt.Sprintf("Too many connections: %d clients are connected, max_connections is %d", f.Number(151, 300), 151)
+		}},
+	},
+}
+
+// NewMySQLErrorLog creates a log string with MySQL 8 error log format
+func NewMySQLErrorLog(f *gofakeit.Faker, t time.Time, level string) string {
+	messages, ok := mysqlMessages[level]
+	if !ok {
+		messages = mysqlMessages[levelInfo]
+	}
+	m := messages[f.IntN(len(messages))]
+	return fmt.Sprintf(
+		MySQLErrorLog,
+		t.UTC().Format(MySQL),
+		f.Number(1, 5000),
+		mysqlPriorities[level],
+		m.code,
+		m.subsystem,
+		m.message(f),
+	)
+}
+
+// NewMySQLSlowLog creates an entry of the MySQL slow query log, spanning several lines
+func NewMySQLSlowLog(f *gofakeit.Faker, t time.Time) string {
+	user := f.RandomString([]string{"app", "reporting", "replicator"})
+	return fmt.Sprintf(
+		MySQLSlowLog,
+		t.UTC().Format(MySQL),
+		user,
+		user,
+		FakeIP(f),
+		f.Number(1, 5000),
+		f.Float64Range(1, 60),
+		f.Float64Range(0, 0.01),
+		f.Number(0, 1000),
+		f.Number(1000, 10000000),
+		t.Unix(),
+		strings.ReplaceAll(RandSQL(f), "now() - interval", "NOW() - INTERVAL"),
+	)
+}
+
+// redisMessage is a message of the Redis log, written by the process with role.
+type redisMessage struct {
+	role    string
+	message func(f *gofakeit.Faker, pid int) string
+}
+
+// redisLevels are the characters Redis marks the levels with.
+var redisLevels = map[string]string{levelDebug: ".", levelInfo: "*", levelWarn: "#", levelError: "#"}
+
+var redisMessages = map[string][]redisMessage{
+	levelDebug: {
+		{"M", func(f *gofakeit.Faker, _ int) string {
+			return fmt.Sprintf("DB 0: %d keys (%d volatile) in %d slots HT.", f.Number(1000, 1000000), f.Number(0, 1000), f.Number(1024, 1048576))
+		}},
+		{"M", func(f *gofakeit.Faker, _ int) string {
+			return fmt.Sprintf("%d clients connected (%d replicas), %d bytes in use", f.Number(1, 500), f.Number(0, 2), f.Number(1000000, 900000000))
+		}},
+	},
+	levelInfo: {
+		{"M", func(f *gofakeit.Faker, _ int) string {
+			return fmt.Sprintf("%d changes in %d seconds. Saving...", f.Number(100, 100000), f.RandomInt([]int{60, 300, 3600}))
+		}},
+		{"M", func(f *gofakeit.Faker, pid int) string {
+			return fmt.Sprintf("Background saving started by pid %d", pid+f.Number(1, 1000))
+		}},
+		{"C", func(f *gofakeit.Faker, _ int) string { return "DB saved on disk" }},
+		{"C", func(f *gofakeit.Faker, _ int) string {
+			return fmt.Sprintf("Fork CoW for RDB: current %d MB, peak %d MB, average %d MB", f.Number(0, 50), f.Number(1, 100), f.Number(0, 50))
+		}},
+		{"M", func(f *gofakeit.Faker, _ int) string { return "Background saving terminated with success" }},
+		{"S", func(f *gofakeit.Faker, _ int) string {
+			return fmt.Sprintf("MASTER <-> REPLICA sync: Finished with success, %d bytes", f.Number(1000000, 900000000))
+		}},
+	},
+	levelWarn: {
+		{"M", func(f *gofakeit.Faker, _ int) string {
+			return "WARNING: The TCP backlog setting of 511 cannot be enforced because /proc/sys/net/core/somaxconn is set to the lower value of 128."
+		}},
+		{"M", func(f *gofakeit.Faker, _ int) string {
+			return "WARNING Memory overcommit must be enabled! Without it, a background save or replication may fail under low memory condition."
+		}},
+		{"M", func(f *gofakeit.Faker, _ int) string {
+			return fmt.Sprintf("Client id=%d addr=%s:%d laddr=10.0.0.5:6379 fd=%d name= age=%d idle=0 flags=N db=0 sub=0 psub=0 cmd=client|list scheduled to be closed ASAP for overcoming of output buffer limits.", f.Number(1, 100000), FakeIP(f), f.Number(30000, 65535), f.Number(8, 1000), f.Number(1, 10000))
+		}},
+	},
+	levelError: {
+		{"S", func(f *gofakeit.Faker, _ int) string { return "Connection with master lost." }},
+		{"S", func(f *gofakeit.Faker, _ int) string { return "Error condition on socket for SYNC: Connection refused" }},
+		{"M", func(f *gofakeit.Faker, _ int) string { return "Can't save in background: fork: Cannot allocate memory" }},
+		{"C", func(f *gofakeit.Faker, pid int) string {
+			return fmt.Sprintf("Write error saving DB on disk: No space left on device (temp-%d.rdb)", pid+f.Number(1, 1000))
+		}},
+	},
+}
+
+// NewRedisLog creates a log string with Redis server format, written by pid
+func NewRedisLog(f *gofakeit.Faker, t time.Time, level string, pid int) string {
+	messages, ok := redisMessages[level]
+	if !ok {
+		messages = redisMessages[levelInfo]
+	}
+	m := messages[f.IntN(len(messages))]
+	if m.role == "C" {
+		// The RDB child has a pid of its own.
+		pid += f.Number(1, 1000)
+	}
+	return fmt.Sprintf(
+		RedisLog,
+		pid,
+		m.role,
+		t.UTC().Format(Redis),
+		redisLevels[level],
+		m.message(f, pid),
+	)
+}
+
+// kafkaMessage is a message of a Kafka broker, with the class logging it.
+type kafkaMessage struct {
+	logger  string
+	message func(f *gofakeit.Faker, t time.Time, broker int) string
+}
+
+func kafkaPartition(f *gofakeit.Faker) string {
+	return fmt.Sprintf("%s-%d", topics[f.IntN(len(topics))], f.Number(0, 11))
+}
+
+var kafkaMessages = map[string][]kafkaMessage{
+	levelDebug: {
+		{"kafka.controller.KafkaController", func(f *gofakeit.Faker, _ time.Time, broker int) string {
+			return fmt.Sprintf("[Controller id=%d] Topics not in preferred replica for broker %d HashMap()", broker, f.Number(1, 3))
+		}},
+	},
+	levelInfo: {
+		{"kafka.log.LocalLog", func(f *gofakeit.Faker, _ time.Time, _ int) string {
+			return fmt.Sprintf("[LocalLog partition=%s, dir=/var/lib/kafka/data] Rolled new log segment at offset %d in %d ms.", kafkaPartition(f), f.Number(1000, 100000000), f.Number(0, 20))
+		}},
+		{"kafka.log.ProducerStateManager", func(f *gofakeit.Faker, _ time.Time, _ int) string {
+			return fmt.Sprintf("[ProducerStateManager partition=%s] Wrote producer snapshot at offset %d with %d producer ids in %d ms.", kafkaPartition(f), f.Number(1000, 100000000), f.Number(0, 10), f.Number(0, 20))
+		}},
+		{"kafka.coordinator.group.GroupCoordinator", func(f *gofakeit.Faker, _ time.Time, broker int) string {
+			group := f.RandomString([]string{"order-processor", "payment-listener", "inventory-sync"})
+			return fmt.Sprintf("[GroupCoordinator %d]: Preparing to rebalance group %s in state PreparingRebalance with old generation %d (__consumer_offsets-%d) (reason: Adding new member %s-%s with group instance id None)", broker, group, f.Number(1, 500), f.Number(0, 49), group, f.UUID())
+		}},
+		{"kafka.log.LogCleaner", func(f *gofakeit.Faker, _ time.Time, _ int) string {
+			return fmt.Sprintf("[kafka-log-cleaner-thread-0]: Log cleaner thread 0 cleaned log __consumer_offsets-%d (dirty section = [%d, %d])", f.Number(0, 49), f.Number(0, 1000), f.Number(1000, 100000))
+		}},
+	},
+	levelWarn: {
+		{"kafka.cluster.Partition", func(f *gofakeit.Faker, t time.Time, broker int) string {
+			return fmt.Sprintf("[Partition %s broker=%d] Shrinking ISR from %d,%d,%d to %d,%d. Leader: (highWatermark: %d, endOffset: %d). Out of sync replicas: (brokerId: %d, endOffset: %d, lastCaughtUpTimeMs: %d).", kafkaPartition(f), broker, broker, broker%3+1, (broker+1)%3+1, broker, broker%3+1, f.Number(1000, 100000), f.Number(100000, 200000), (broker+1)%3+1, f.Number(1000, 100000), t.Add(-time.Duration(f.Number(30, 600))*time.Second).UnixMilli())
+		}},
+		{"kafka.server.ReplicaFetcherThread", func(f *gofakeit.Faker, _ time.Time, broker int) string {
+			return fmt.Sprintf("[ReplicaFetcher replicaId=%d, leaderId=%d, fetcherId=0] Error in response for fetch request (type=FetchRequest, replicaId=%d, maxWait=500, minBytes=1, maxBytes=10485760)", broker, broker%3+1, broker)
+		}},
+	},
+	levelError: {
+		{"kafka.server.ReplicaFetcherThread", func(f *gofakeit.Faker, _ time.Time, broker int) string {
+			return fmt.Sprintf("[ReplicaFetcher replicaId=%d, leaderId=%d, fetcherId=0] Error for partition %s at offset %d", broker, broker%3+1, kafkaPartition(f), f.Number(1000, 100000000))
+		}},
+		{"state.change.logger", func(f *gofakeit.Faker, _ time.Time, broker int) string {
+			epoch := f.Number(1, 50)
+			return fmt.Sprintf("[Controller id=%d epoch=%d] Controller %d epoch %d failed to change state for partition %s from OfflinePartition to OnlinePartition", broker, epoch, broker, epoch, kafkaPartition(f))
+		}},
+	},
+}
+
+// NewKafkaLog creates a log string with Kafka broker (log4j) format, written by the broker with id broker
+func NewKafkaLog(f *gofakeit.Faker, t time.Time, level string, broker int) string {
+	messages, ok := kafkaMessages[level]
+	if !ok {
+		messages = kafkaMessages[levelInfo]
+	}
+	m := messages[f.IntN(len(messages))]
+	return fmt.Sprintf(
+		KafkaLog,
+		t.UTC().Format(Kafka),
+		strings.ToUpper(level),
+		m.message(f, t, broker),
+		m.logger,
+	)
+}
@@ -0,0 +1,59 @@
+package flog
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInfraLogs(t *testing.T) {
+	f := gofakeit.New(1)
+	ts := time.Date(2024, 3, 5, 7, 8, 9, 123456000, time.UTC)
+	for _, level := range []string{levelDebug, levelInfo, levelWarn, levelError} {
+		for i := 0; i < 20; i++ {
+			assert.Regexp(t, `^2024/03/05 07:08:09 \[(debug|info|warn|error|crit)\] 12#0: \*\d+ `, NewNginxErrorLog(f, ts, level, 12))
+			assert.Regexp(t, `^2024-03-05T07:08:09.123456Z \d+ \[(Note|Warning|ERROR)\] \[MY-\d{6}\] \[(Server|InnoDB|Repl)\] `, NewMySQLErrorLog(f, ts, level))
+			assert.Regexp(t, `^\[2024-03-05 07:08:09,123\] (DEBUG|INFO|WARN|ERROR) .+ \([a-zA-Z.]+\)$`, NewKafkaLog(f, ts, level, 2))
+
+			redis := NewRedisLog(f, ts, level, 4242)
+			assert.Regexp(t, `^\d+:[MSC] 05 Mar 2024 07:08:09.123 [.*#] `, redis)
+			if !strings.Contains(redis, ":C ") {
+				assert.True(t, strings.HasPrefix(redis, "4242:"), "the server writes with its pid")
+			}
+		}
+	}
+
+	slow := NewMySQLSlowLog(f, ts)
+	assert.Regexp(t, regexp.MustCompile(`^# Time: 2024-03-05T07:08:09.123456Z\n# User@Host: \w+\[\w+\] @  \[[0-9.]+\]  Id: +\d+\n# Query_time: [0-9.]+  Lock_time: [0-9.]+ Rows_sent: \d+  Rows_examined: \d+\nSET timestamp=1709622489;\n.+;$`), slow)
+}
+
+func TestPostgresLog(t *testing.T) {
+	f := gofakeit.New(1)
+	ts := time.Date(2024, 3, 5, 7, 8, 9, 123000000, time.UTC)
+	line := regexp.MustCompile(`^2024-03-05 07:08:09.123 UTC \[(\d+)\] (DEBUG1|LOG|WARNING|ERROR|FATAL|DETAIL|HINT|CONTEXT|STATEMENT):  `)
+	deadlocks := 0
+	for i := 0; i < 200; i++ {
+		entry := NewPostgresLog(f, ts, levelError, 1000)
+		lines := strings.Split(entry, "\n")
+		m := line.FindStringSubmatch(lines[0])
+		if assert.NotNil(t, m, lines[0]) {
+			// Continuation lines have the prefix of the entry, or a tab.
+			for _, l := range lines[1:] {
+				if !strings.HasPrefix(l, "\t") {
+					assert.True(t, strings.HasPrefix(l, fmt.Sprintf("2024-03-05 07:08:09.123 UTC [%s] ", m[1])), l)
+				}
+			}
+		}
+		if strings.Contains(entry, "deadlock detected") {
+			deadlocks++
+			assert.Contains(t, entry, "DETAIL:  Process "+m[1]+" waits for ShareLock")
+		}
+	}
+	assert.Positive(t, deadlocks)
+	assert.Regexp(t, `LOG:  `, NewPostgresLog(f, ts, levelInfo, 1000))
+}
@@ -3,11 +3,10 @@ package flog
 import (
 	"encoding/json"
 	"fmt"
-	"math/rand"
 	"strings"
 	"time"
 
-	"github.com/brianvoe/gofakeit"
+	"github.com/brianvoe/gofakeit/v7"
 	"github.com/grafana/loki/pkg/push"
 )
 
@@ -21,7 +20,7 @@ const (
 	// RFC3164Log : <priority>{timestamp} {hostname} {application}[{pid}]: {message}
 	RFC3164Log = "<%d>%s %s %s[%d]: %s"
 	// RFC5424Log : <priority>{version} {iso-timestamp} {hostname} {application} {pid} {message-id} {structured-data} {message}
-	RFC5424Log = "<%d>%d %s %s %s %d ID%d %s %s"
+	RFC5424Log = "<%d>%d %s %s %s %s %s %s %s"
 	// CommonLogFormat : {host} {user-identifier} {auth-user-id} [{datetime}] "{method} {request} {protocol}" {response-code} {bytes}
 	CommonLogFormat = "%s - %s [%s] \"%s %s %s\" %d %d"
   // JSONLogFormat : {"host": "{host}", "user-identifier": "{user-identifier}", "datetime": "{datetime}", "method": "{method}", "request": "{request}", "protocol": "{protocol}", "status": {status}, "bytes": {bytes}, "referer": "{referer}", "_25values": "{_25values}", "msg": "{msg}", "nested_object": "{nested_object}"}
@@ -30,105 +29,109 @@ const (
 )
 
 // NewApacheCommonLog creates a log string with apache common log format
-func NewApacheCommonLog(t time.Time, URI string, statusCode int) string {
+func NewApacheCommonLog(f *gofakeit.Faker, t time.Time, URI string, statusCode int) string {
 	return fmt.Sprintf(
 		ApacheCommonLog,
-		gofakeit.IPv4Address(),
-		RandAuthUserID(),
+		f.IPv4Address(),
+		RandAuthUserID(f),
 		t.Format(Apache),
-		gofakeit.HTTPMethod(),
+		f.HTTPMethod(),
 		URI,
-		RandHTTPVersion(),
+		RandHTTPVersion(f),
 		statusCode,
-		gofakeit.Number(0, 30000),
+		f.Number(0, 30000),
 	)
 }
 
-var ips = []string{
-	gofakeit.IPv4Address(),
-	gofakeit.IPv4Address(),
-	gofakeit.IPv4Address(),
-	gofakeit.IPv4Address(),
-	gofakeit.IPv4Address(),
+var ips []string
+
+func randIPs(f *gofakeit.Faker) []string {
+	return []string{
+		f.IPv4Address(),
+		f.IPv4Address(),
+		f.IPv4Address(),
+		f.IPv4Address(),
+		f.IPv4Address(),
+	}
 }
 
-func FakeIP() string {
-	return ips[rand.Intn(len(ips))]
+func FakeIP(f *gofakeit.Faker) string {
+	return ips[f.IntN(len(ips))]
 }
 
 // NewApacheCombinedLog creates a log string with apache combined log format
-func NewApacheCombinedLog(t time.Time, URI string, statusCode int) string {
+func NewApacheCombinedLog(f *gofakeit.Faker, t time.Time, URI string, statusCode int) string {
 	return fmt.Sprintf(
 		ApacheCombinedLog,
-		ips[rand.Intn(len(ips))],
-		RandAuthUserID(),
+		ips[f.IntN(len(ips))],
+		RandAuthUserID(f),
 		t.Format(Apache),
-		gofakeit.HTTPMethod(),
+		f.HTTPMethod(),
 		URI,
-		RandHTTPVersion(),
+		RandHTTPVersion(f),
 		statusCode,
-		gofakeit.Number(30, 100000),
-		gofakeit.URL(),
-		gofakeit.UserAgent(),
+		f.Number(30, 100000),
+		f.URL(),
+		f.UserAgent(),
 	)
 }
 
 // NewApacheErrorLog creates a log string with apache error log format
-func NewApacheErrorLog(t time.Time) string {
+func NewApacheErrorLog(f *gofakeit.Faker, t time.Time) string {
 	return fmt.Sprintf(
 		ApacheErrorLog,
 		t.Format(ApacheError),
-		gofakeit.Word(),
-		gofakeit.LogLevel("apache"),
-		gofakeit.Number(1, 10000),
-		gofakeit.Number(1, 10000),
-		gofakeit.IPv4Address(),
-		gofakeit.Number(1, 65535),
-		gofakeit.HackerPhrase(),
+		f.Word(),
+		f.LogLevel("apache"),
+		f.Number(1, 10000),
+		f.Number(1, 10000),
+		f.IPv4Address(),
+		f.Number(1, 65535),
+		f.HackerPhrase(),
 	)
 }
 
 // NewRFC3164Log creates a log string with syslog (RFC3164) format
-func NewRFC3164Log(t time.Time) string {
+func NewRFC3164Log(f *gofakeit.Faker, t time.Time) string {
 	return fmt.Sprintf(
 		RFC3164Log,
-		gofakeit.Number(0, 191),
+		f.Number(0, 191),
 		t.Format(RFC3164),
-		strings.ToLower(gofakeit.Username()),
-		gofakeit.Word(),
-		gofakeit.Number(1, 10000),
-		gofakeit.HackerPhrase(),
+		strings.ToLower(f.Username()),
+		f.Word(),
+		f.Number(1, 10000),
+		f.HackerPhrase(),
 	)
 }
 
 // NewRFC5424Log creates a log string with syslog (RFC5424) format
-func NewRFC5424Log(t time.Time) string {
+func NewRFC5424Log(f *gofakeit.Faker, t time.Time) string {
 	return fmt.Sprintf(
 		RFC5424Log,
-		gofakeit.Number(0, 191),
-		gofakeit.Number(1, 3),
+		f.Number(0, 191),
+		1,
 		t.Format(RFC5424),
-		gofakeit.DomainName(),
-		gofakeit.Word(),
-		gofakeit.Number(1, 10000),
-		gofakeit.Number(1, 1000),
-		"-", // TODO: structured data
-		gofakeit.HackerPhrase(),
+		f.DomainName(),
+		f.Word(),
+		RandProcID(f),
+		RandMsgID(f),
+		RandStructuredData(f),
+		f.HackerPhrase(),
 	)
 }
 
 // NewCommonLogFormat creates a log string with common log format
-func NewCommonLogFormat(t time.Time, URI string, statusCode int) string {
+func NewCommonLogFormat(f *gofakeit.Faker, t time.Time, URI string, statusCode int) string {
 	return fmt.Sprintf(
 		CommonLogFormat,
-		gofakeit.IPv4Address(),
-		RandAuthUserID(),
+		f.IPv4Address(),
+		RandAuthUserID(f),
 		t.Format(CommonLog),
-		gofakeit.HTTPMethod(),
+		f.HTTPMethod(),
 		URI,
-		RandHTTPVersion(),
+		RandHTTPVersion(f),
 		statusCode,
-		gofakeit.Number(0, 30000),
+		f.Number(0, 30000),
 	)
 }
 
@@ -155,13 +158,13 @@ type NestedJsonObject struct {
 }
 
 // Helper function to initialize BaseObject
-func newBaseObject() BaseObject {
+func newBaseObject(f *gofakeit.Faker) BaseObject {
 	return BaseObject{
-		Method:         gofakeit.HTTPMethod(),
-		Url:            gofakeit.URL(),
-		UserIdentifier: gofakeit.Username(),
-		NumArray:       []int{gofakeit.Number(0, 30000), gofakeit.Number(0, 30000), gofakeit.Number(0, 30000)},
-		StrArray:       []string{gofakeit.Word(), gofakeit.Word(), gofakeit.Word()},
+		Method:         f.HTTPMethod(),
+		Url:            f.URL(),
+		UserIdentifier: f.Username(),
+		NumArray:       []int{f.Number(0, 30000), f.Number(0, 30000), f.Number(0, 30000)},
+		StrArray:       []string{f.Word(), f.Word(), f.Word()},
 	}
 }
 
@@ -182,7 +185,7 @@ var sentenceWeights = []int{
 }
 
 // weightedRandomSentence returns a random sentence from the sentences slice with weights
-func weightedRandomSentence() string {
+func weightedRandomSentence(f *gofakeit.Faker) string {
 	// Calculate total weight
 	totalWeight := 0
 	for _, weight := range sentenceWeights {
@@ -190,7 +193,7 @@ func weightedRandomSentence() string {
 	}
 
 	// Generate random number between 0 and totalWeight
-	r := rand.Intn(totalWeight)
+	r := f.IntN(totalWeight)
 
 	// Find the sentence based on the random number
 	for i, weight := range sentenceWeights {
@@ -205,13 +208,13 @@ func weightedRandomSentence() string {
 }
 
 // NewJSONLogFormat creates a log string with json log format
-func NewJSONLogFormat(t time.Time, URI string, statusCode int) string {
+func NewJSONLogFormat(f *gofakeit.Faker, t time.Time, URI string, statusCode int) string {
 	nestedJsonObject := &NestedJsonObject{
-		BaseObject: newBaseObject(),
+		BaseObject: newBaseObject(f),
 		DeeplyNestedObject: DeeplyNestedObject{
-			BaseObject: newBaseObject(),
+			BaseObject: newBaseObject(f),
 			ExtraDeeplyNestedObject: ExtraDeeplyNestedObject{
-				BaseObject: newBaseObject(),
+				BaseObject: newBaseObject(f),
 			},
 		},
 	}
@@ -220,46 +223,46 @@ func NewJSONLogFormat(t time.Time, URI string, statusCode int) string {
   // JSONLogFormat : {"host": "{host}", "user-identifier": "{user-identifier}", "datetime": "{datetime}", "method": "{method}", "request": "{request}", "protocol": "{protocol}", "status": {status}, "bytes": {bytes}, "referer": "{referer}", "_25values": "{_25values}", "msg": "{msg}", "nested_object": "{nested_object}"}
 	return fmt.Sprintf(
 		JSONLogFormat,
-		ips[rand.Intn(len(ips))],
-		RandAuthUserID(),
+		ips[f.IntN(len(ips))],
+		RandAuthUserID(f),
 		t.Format(CommonLog),
-		gofakeit.HTTPMethod(),
+		f.HTTPMethod(),
 		URI,
-		RandHTTPVersion(),
+		RandHTTPVersion(f),
 		statusCode,
-		gofakeit.Number(0, 300),
-		gofakeit.URL(),
-		gofakeit.Number(0, 25),
-		weightedRandomSentence(),
+		f.Number(0, 300),
+		f.URL(),
+		f.Number(0, 25),
+		weightedRandomSentence(f),
 		nestedJson,
 	)
 }
 
 // "Order %d successfully placed, customerId: %s, price: %f, paymentMethod: %s, shippingMethod: %s, shippingCountry: %s"
-func NewShoppingCart(t time.Time) string {
+func NewShoppingCart(f *gofakeit.Faker, t time.Time) string {
 	return fmt.Sprintf(
 		ShoppingCartLogFormat,
-		gofakeit.Number(1, 10000),
-		gofakeit.UUID(),
-		gofakeit.Price(10.0, 1000.0),
-		gofakeit.CreditCardType(),
-		RandShippingMethod(),
-		gofakeit.CountryAbr(),
+		f.Number(1, 10000),
+		f.UUID(),
+		f.Price(10.0, 1000.0),
+		f.CreditCardType(),
+		RandShippingMethod(f),
+		f.CountryAbr(),
 	)
 }
 
-func NewShoppingCartWithMetadata(t time.Time) (string, push.LabelsAdapter) {
-	orderId := gofakeit.Number(1, 10000)
-	customerId := gofakeit.UUID()
-	price := gofakeit.Price(10.0, 1000.0)
-	paymentMethod := gofakeit.CreditCardType()
-	shippingMethod := RandShippingMethod()
+func NewShoppingCartWithMetadata(f *gofakeit.Faker, t time.Time) (string, push.LabelsAdapter) {
+	orderId := f.Number(1, 10000)
+	customerId := f.UUID()
+	price := f.Price(10.0, 1000.0)
+	paymentMethod := f.CreditCardType()
+	shippingMethod := RandShippingMethod(f)
 
   var country string
   if price > 700.0 { 
     country = "US"
   } else {
-	  country = gofakeit.CountryAbr()
+	  country = f.CountryAbr()
   }
 
 	return fmt.Sprintf(
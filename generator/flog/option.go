@@ -27,6 +27,14 @@ Options:
                            - rfc3164
                            - rfc5424
                            - json
+                           - nginx_error
+                           - postgres
+                           - mysql_error
+                           - mysql_slow
+                           - redis
+                           - kafka
+                           - windows_event
+                           - windows_event_xml
   -o, --output string      output filename. Path-like is allowed. (default "generated.log")
   -t, --type string        log output type. available types:
                            - stdout (default)
@@ -48,7 +56,7 @@ Options:
 )
 
 var (
-	validFormats = []string{"apache_common", "apache_combined", "apache_error", "rfc3164", "rfc5424", "common_log", "json"}
+	validFormats = []string{"apache_common", "apache_combined", "apache_error", "rfc3164", "rfc5424", "common_log", "json", "nginx_error", "postgres", "mysql_error", "mysql_slow", "redis", "kafka", "windows_event", "windows_event_xml"}
 	validTypes   = []string{"stdout", "log", "gz"}
 )
 
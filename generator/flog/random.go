@@ -1,49 +1,56 @@
 package flog
 
 import (
-	"math/rand"
 	"net/url"
 	"strings"
 
-	"github.com/brianvoe/gofakeit"
+	"github.com/brianvoe/gofakeit/v7"
 )
 
 var ressourceURIs []string
 
 func init() {
+	Seed(gofakeit.GlobalFaker)
+}
+
+// Seed regenerates the value pools shared by all logs (IPs, resource URIs) from f,
+// so that a seeded run draws the same values.
+func Seed(f *gofakeit.Faker) {
+	ips = randIPs(f)
+	ressourceURIs = nil
 	for i := 0; i < 20; i++ {
-		ressourceURIs = append(ressourceURIs, randResourceURI())
+		ressourceURIs = append(ressourceURIs, randResourceURI(f))
 	}
 }
 
 // RandResourceURI generates a random resource URI
-func RandResourceURI() string {
-	return ressourceURIs[rand.Intn(len(ressourceURIs))]
+func RandResourceURI(f *gofakeit.Faker) string {
+	return ressourceURIs[f.IntN(len(ressourceURIs))]
 }
 
-func randResourceURI() string {
+func randResourceURI(f *gofakeit.Faker) string {
 	var uri string
-	num := gofakeit.Number(1, 4)
+	num := f.Number(1, 4)
 	for i := 0; i < num; i++ {
-		uri += "/" + url.QueryEscape(gofakeit.BS())
+		uri += "/" + url.QueryEscape(f.BS())
 	}
 	uri = strings.ToLower(uri)
 	return uri
 }
 
 // RandAuthUserID generates a random auth user id
-func RandAuthUserID() string {
-	candidates := []string{"-", strings.ToLower(gofakeit.Username())}
-	return candidates[rand.Intn(2)]
+func RandAuthUserID(f *gofakeit.Faker) string {
+	candidates := []string{"-", strings.ToLower(f.Username())}
+	return candidates[f.IntN(2)]
 }
 
 // RandHTTPVersion returns a random http version
-func RandHTTPVersion() string {
+func RandHTTPVersion(f *gofakeit.Faker) string {
 	versions := []string{"HTTP/1.0", "HTTP/1.1", "HTTP/2.0"}
-	return versions[rand.Intn(3)]
+	return versions[f.IntN(3)]
 }
 
-func RandShippingMethod() string {
+func RandShippingMethod(f *gofakeit.Faker) string {
 	versions := []string{"express", "ground", "air", "2-day", "next-day"}
-	return versions[rand.Intn(5)]
+	return versions[f.IntN(5)]
 }
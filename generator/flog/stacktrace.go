@@ -0,0 +1,409 @@
+package flog
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v7"
+)
+
+// Runtimes are the runtimes NewStackTrace and NewRuntimeLog write the logs of.
+var Runtimes = []string{"java", "python", "go", "node", "dotnet", "ruby"}
+
+// stackTraces writes a trace of depth frames.
+var stackTraces = map[string]func(f *gofakeit.Faker, depth int) string{
+	"java":   javaStackTrace,
+	"python": pythonTraceback,
+	"go":     goPanic,
+	"node":   nodeStackTrace,
+	"dotnet": dotnetStackTrace,
+	"ruby":   rubyBacktrace,
+}
+
+// NewStackTrace returns a multi-line stack trace of runtime, between 3 and 20 frames deep, the exception first.
+// Java, Python and .NET traces may chain their causes, Go panics dump the other goroutines.
+func NewStackTrace(f *gofakeit.Faker, runtime string) string {
+	trace, ok := stackTraces[runtime]
+	if !ok {
+		panic(fmt.Sprintf("unknown runtime %q", runtime))
+	}
+	return trace(f, f.Number(3, 20))
+}
+
+// NewRuntimeLog returns a line written at level by the usual logger of runtime. Error lines are followed by a
+// stack trace, Go errors are the panic alone as the runtime writes it.
+func NewRuntimeLog(f *gofakeit.Faker, t time.Time, runtime, level string) string {
+	msg := runtimeMessages[f.IntN(len(runtimeMessages))](f)
+	if level == "error" {
+		msg = runtimeErrors[f.IntN(len(runtimeErrors))](f)
+	}
+	var line string
+	switch runtime {
+	case "java":
+		line = fmt.Sprintf("%s %5s 1 --- [nio-8080-exec-%d] %-40s : %s", t.Format("2006-01-02 15:04:05.000"), strings.ToUpper(level), f.Number(1, 10), javaLogger(f), msg)
+	case "python":
+		line = fmt.Sprintf("%s %s [%s] %s", t.Format("2006-01-02 15:04:05,000"), strings.ToUpper(level), pythonModules[f.IntN(len(pythonModules))], msg)
+	case "go":
+		if level == "error" {
+			return NewStackTrace(f, runtime)
+		}
+		line = fmt.Sprintf("time=%s level=%s msg=%q", t.Format(time.RFC3339Nano), strings.ToUpper(level), msg)
+	case "node":
+		line = fmt.Sprintf("[%s] %s: %s", t.UTC().Format("2006-01-02T15:04:05.000Z"), strings.ToUpper(level), msg)
+	case "dotnet":
+		line = fmt.Sprintf("%s [%s] %s", t.Format("2006-01-02 15:04:05.000 -07:00"), dotnetLevels[level], msg)
+	case "ruby":
+		line = fmt.Sprintf("%s, [%s #1] %5s -- : %s", strings.ToUpper(level[:1]), t.Format("2006-01-02T15:04:05.000000"), strings.ToUpper(level), msg)
+	default:
+		panic(fmt.Sprintf("unknown runtime %q", runtime))
+	}
+	if level == "error" {
+		line += "\n" + NewStackTrace(f, runtime)
+	}
+	return line
+}
+
+var runtimeMessages = []func(f *gofakeit.Faker) string{
+	func(f *gofakeit.Faker) string {
+		return fmt.Sprintf("Processed order %d in %dms", f.Number(1, 100000), f.Number(1, 900))
+	},
+	func(f *gofakeit.Faker) string {
+		return fmt.Sprintf("Fetched %d items from cart %s", f.Number(1, 20), f.UUID())
+	},
+	func(f *gofakeit.Faker) string { return fmt.Sprintf("Cache miss for key product:%d", f.Number(1, 5000)) },
+	func(f *gofakeit.Faker) string {
+		return fmt.Sprintf("Payment authorized for customer %d", f.Number(1, 100000))
+	},
+	func(f *gofakeit.Faker) string {
+		return fmt.Sprintf("Connection pool stats active=%d idle=%d", f.Number(0, 20), f.Number(0, 20))
+	},
+}
+
+var runtimeErrors = []func(f *gofakeit.Faker) string{
+	func(f *gofakeit.Faker) string { return fmt.Sprintf("Failed to process order %d", f.Number(1, 100000)) },
+	func(f *gofakeit.Faker) string { return "Unhandled exception while handling request" },
+	func(f *gofakeit.Faker) string {
+		return fmt.Sprintf("Payment for customer %d failed", f.Number(1, 100000))
+	},
+}
+
+var dotnetLevels = map[string]string{"debug": "DBG", "info": "INF", "warn": "WRN", "error": "ERR"}
+
+// frame is a function of a trace, in the file it is defined in.
+type frame struct {
+	pkg, class, method, file string
+}
+
+var javaFrames = []frame{
+	{"com.example.shop.order", "OrderService", "process", "OrderService.java"},
+	{"com.example.shop.order", "OrderController", "create", "OrderController.java"},
+	{"com.example.shop.cart", "CartRepository", "findById", "CartRepository.java"},
+	{"com.example.shop.payment", "PaymentClient", "authorize", "PaymentClient.java"},
+	{"com.example.shop.inventory", "StockService", "reserve", "StockService.java"},
+	{"com.example.shop.order", "OrderValidator", "validate", "OrderValidator.java"},
+	{"org.springframework.web.servlet", "FrameworkServlet", "service", "FrameworkServlet.java"},
+	{"org.springframework.web.servlet", "DispatcherServlet", "doDispatch", "DispatcherServlet.java"},
+	{"org.springframework.aop.framework", "ReflectiveMethodInvocation", "proceed", "ReflectiveMethodInvocation.java"},
+	{"org.springframework.transaction.interceptor", "TransactionInterceptor", "invoke", "TransactionInterceptor.java"},
+	{"org.apache.catalina.core", "ApplicationFilterChain", "doFilter", "ApplicationFilterChain.java"},
+	{"org.apache.tomcat.util.net", "NioEndpoint$SocketProcessor", "doRun", "NioEndpoint.java"},
+	{"java.base/java.util.concurrent", "ThreadPoolExecutor", "runWorker", "ThreadPoolExecutor.java"},
+}
+
+var javaExceptions = []func(f *gofakeit.Faker) string{
+	func(f *gofakeit.Faker) string { return "java.lang.NullPointerException" },
+	func(f *gofakeit.Faker) string {
+		return fmt.Sprintf("java.lang.IllegalStateException: Order %d is not in a payable state", f.Number(1, 100000))
+	},
+	func(f *gofakeit.Faker) string {
+		return fmt.Sprintf("java.util.concurrent.TimeoutException: Timed out after %dms waiting for payment-service", f.Number(1000, 30000))
+	},
+	func(f *gofakeit.Faker) string {
+		return "java.sql.SQLTransientConnectionException: HikariPool-1 - Connection is not available, request timed out after 30000ms."
+	},
+	func(f *gofakeit.Faker) string {
+		return fmt.Sprintf("java.lang.ArrayIndexOutOfBoundsException: Index %d out of bounds for length %d", f.Number(5, 10), f.Number(0, 5))
+	},
+}
+
+func javaLogger(f *gofakeit.Faker) string {
+	fr := javaFrames[f.IntN(len(javaFrames)/2)]
+	var b strings.Builder
+	for _, part := range strings.Split(fr.pkg, ".") {
+		b.WriteString(part[:1] + ".")
+	}
+	return b.String() + fr.class
+}
+
+func javaFrame(f *gofakeit.Faker, fr frame) string {
+	return fmt.Sprintf("\tat %s.%s.%s(%s:%d)", fr.pkg, fr.class, fr.method, fr.file, f.Number(20, 900))
+}
+
+func javaStackTrace(f *gofakeit.Faker, depth int) string {
+	lines := []string{javaExceptions[f.IntN(len(javaExceptions))](f)}
+	for i := 0; i < depth; i++ {
+		lines = append(lines, javaFrame(f, pickFrame(f, javaFrames, i, depth)))
+	}
+	// Causes share the frames of their outer exception, only the innermost ones are printed.
+	for causes := f.IntN(3); causes > 0; causes-- {
+		lines = append(lines, "Caused by: "+javaExceptions[f.IntN(len(javaExceptions))](f))
+		own := f.Number(1, 4)
+		for i := 0; i < own; i++ {
+			lines = append(lines, javaFrame(f, javaFrames[f.IntN(len(javaFrames)/2)]))
+		}
+		lines = append(lines, fmt.Sprintf("\t... %d more", depth))
+	}
+	return strings.Join(lines, "\n")
+}
+
+var pythonModules = []string{"shop.api.orders", "shop.services.payment", "shop.db.session", "celery.worker", "uvicorn.error"}
+
+var pythonFrames = []frame{
+	{"/app/shop/api", "", "create_order", "orders.py"},
+	{"/app/shop/services", "", "process_payment", "payment.py"},
+	{"/app/shop/services", "", "reserve_stock", "inventory.py"},
+	{"/app/shop/db", "", "get_cart", "repository.py"},
+	{"/usr/local/lib/python3.12/site-packages/fastapi", "", "run_endpoint_function", "routing.py"},
+	{"/usr/local/lib/python3.12/site-packages/starlette", "", "__call__", "routing.py"},
+	{"/usr/local/lib/python3.12/site-packages/sqlalchemy/engine", "", "_execute_context", "base.py"},
+	{"/usr/local/lib/python3.12/site-packages/requests", "", "send", "sessions.py"},
+}
+
+var pythonStatements = []string{
+	"order = service.process(payload)",
+	"return await dependant.call(**values)",
+	"response = self.session.post(url, json=body, timeout=5)",
+	"cart = carts[customer_id]",
+	"total = sum(item.price * item.quantity for item in cart.items)",
+	"raise PaymentDeclined(order.id)",
+}
+
+var pythonExceptions = []func(f *gofakeit.Faker) string{
+	func(f *gofakeit.Faker) string { return "KeyError: 'customer_id'" },
+	func(f *gofakeit.Faker) string { return "AttributeError: 'NoneType' object has no attribute 'items'" },
+	func(f *gofakeit.Faker) string { return "ZeroDivisionError: division by zero" },
+	func(f *gofakeit.Faker) string {
+		return fmt.Sprintf("requests.exceptions.ConnectTimeout: HTTPSConnectionPool(host='payments.internal', port=443): Max retries exceeded with url: /v1/charges/%d", f.Number(1, 100000))
+	},
+	func(f *gofakeit.Faker) string {
+		return "sqlalchemy.exc.OperationalError: (psycopg2.OperationalError) server closed the connection unexpectedly"
+	},
+}
+
+func pythonTraceback(f *gofakeit.Faker, depth int) string {
+	var traces []string
+	for chained := f.IntN(2); chained >= 0; chained-- {
+		lines := []string{"Traceback (most recent call last):"}
+		for i := 0; i < depth; i++ {
+			// Python prints the outermost call first.
+			fr := pickFrame(f, pythonFrames, depth-1-i, depth)
+			lines = append(lines,
+				fmt.Sprintf(`  File "%s/%s", line %d, in %s`, fr.pkg, fr.file, f.Number(10, 600), fr.method),
+				"    "+pythonStatements[f.IntN(len(pythonStatements))],
+			)
+		}
+		lines = append(lines, pythonExceptions[f.IntN(len(pythonExceptions))](f))
+		traces = append(traces, strings.Join(lines, "\n"))
+		depth = f.Number(2, 6)
+	}
+	return strings.Join(traces, "\n\nDuring handling of the above exception, another exception occurred:\n\n")
+}
+
+var goFrames = []frame{
+	{"github.com/example/shop/internal/order", "(*Service)", "Process", "/app/internal/order/service.go"},
+	{"github.com/example/shop/internal/order", "(*Handler)", "Create", "/app/internal/order/handler.go"},
+	{"github.com/example/shop/internal/cart", "(*Store)", "Get", "/app/internal/cart/store.go"},
+	{"github.com/example/shop/internal/payment", "(*Client)", "Authorize", "/app/internal/payment/client.go"},
+	{"net/http", "HandlerFunc", "ServeHTTP", "/usr/local/go/src/net/http/server.go"},
+	{"github.com/go-chi/chi/v5", "(*Mux)", "routeHTTP", "/go/pkg/mod/github.com/go-chi/chi/v5@v5.0.12/mux.go"},
+	{"github.com/go-chi/chi/v5/middleware", "", "Recoverer.func1", "/go/pkg/mod/github.com/go-chi/chi/v5@v5.0.12/middleware/recoverer.go"},
+	{"net/http", "serverHandler", "ServeHTTP", "/usr/local/go/src/net/http/server.go"},
+	{"net/http", "(*conn)", "serve", "/usr/local/go/src/net/http/server.go"},
+}
+
+var goPanics = []func(f *gofakeit.Faker) string{
+	func(f *gofakeit.Faker) string {
+		return fmt.Sprintf("panic: runtime error: invalid memory address or nil pointer dereference\n[signal SIGSEGV: segmentation violation code=0x1 addr=0x%x pc=0x%x]", f.Number(0, 0x40), f.Number(0x400000, 0x9fffff))
+	},
+	func(f *gofakeit.Faker) string {
+		return fmt.Sprintf("panic: runtime error: index out of range [%d] with length %d", f.Number(5, 10), f.Number(0, 5))
+	},
+	func(f *gofakeit.Faker) string { return "panic: assignment to entry in nil map" },
+	func(f *gofakeit.Faker) string {
+		return "panic: interface conversion: interface {} is nil, not *order.Order"
+	},
+}
+
+// goWaits are the states of the goroutines dumped after the one panicking, with their top frame.
+var goWaits = []struct{ state, frame, file string }{
+	{"IO wait", "internal/poll.runtime_pollWait(0x7f%x, 0x72)", "/usr/local/go/src/runtime/netpoll.go:345 +0x85"},
+	{"select", "github.com/example/shop/internal/worker.(*Pool).run(0xc000%x)", "/app/internal/worker/pool.go:88 +0x1a5"},
+	{"chan receive", "github.com/example/shop/internal/events.(*Bus).loop(0xc000%x)", "/app/internal/events/bus.go:51 +0x7d"},
+	{"semacquire", "sync.runtime_SemacquireMutex(0xc000%x, 0x0, 0x1)", "/usr/local/go/src/runtime/sema.go:77 +0x25"},
+}
+
+func goFrame(f *gofakeit.Faker, fr frame) string {
+	fn := fr.pkg + "."
+	if fr.class != "" {
+		fn += fr.class + "."
+	}
+	return fmt.Sprintf("%s%s(0xc000%x, {0x%x, 0x%x})\n\t%s:%d +0x%x", fn, fr.method, f.Number(0x10000, 0xfffff), f.Number(0x700000, 0x7fffff), f.Number(1, 64), fr.file, f.Number(20, 900), f.Number(0x10, 0x400))
+}
+
+func goPanic(f *gofakeit.Faker, depth int) string {
+	id := f.Number(20, 5000)
+	lines := []string{goPanics[f.IntN(len(goPanics))](f), "", fmt.Sprintf("goroutine %d [running]:", id)}
+	for i := 0; i < depth; i++ {
+		lines = append(lines, goFrame(f, pickFrame(f, goFrames, i, depth)))
+	}
+	lines = append(lines, fmt.Sprintf("created by net/http.(*Server).Serve in goroutine 1\n\t/usr/local/go/src/net/http/server.go:3285 +0x%x", f.Number(0x10, 0x600)))
+	for others := f.IntN(4); others > 0; others-- {
+		wait := goWaits[f.IntN(len(goWaits))]
+		lines = append(lines,
+			"",
+			fmt.Sprintf("goroutine %d [%s, %d minutes]:", f.Number(1, id), wait.state, f.Number(1, 30)),
+			fmt.Sprintf(wait.frame, f.Number(0x10000, 0xfffff)),
+			"\t"+wait.file,
+		)
+		for i := f.Number(1, 3); i > 0; i-- {
+			lines = append(lines, goFrame(f, goFrames[f.Number(4, len(goFrames)-1)]))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+var nodeFrames = []frame{
+	{"/app/src/services", "OrderService", "process", "order.js"},
+	{"/app/src/services", "PaymentService", "charge", "payment.js"},
+	{"/app/src/routes", "", "createOrder", "orders.js"},
+	{"/app/src/db", "CartRepository", "findById", "cart.js"},
+	{"/app/node_modules/express/lib/router", "Layer", "handle [as handle_request]", "layer.js"},
+	{"/app/node_modules/express/lib/router", "", "next", "route.js"},
+	{"/app/node_modules/express/lib/router", "Function", "process_params", "index.js"},
+	{"node:internal/process", "", "processTicksAndRejections", "task_queues"},
+}
+
+var nodeErrors = []func(f *gofakeit.Faker) string{
+	func(f *gofakeit.Faker) string { return "TypeError: Cannot read properties of undefined (reading 'id')" },
+	func(f *gofakeit.Faker) string { return "ReferenceError: customer is not defined" },
+	func(f *gofakeit.Faker) string {
+		return fmt.Sprintf("Error: connect ECONNREFUSED 10.%d.%d.%d:5432", f.Number(0, 255), f.Number(0, 255), f.Number(1, 254))
+	},
+	func(f *gofakeit.Faker) string { return "RangeError: Maximum call stack size exceeded" },
+}
+
+func nodeStackTrace(f *gofakeit.Faker, depth int) string {
+	lines := []string{nodeErrors[f.IntN(len(nodeErrors))](f)}
+	for i := 0; i < depth; i++ {
+		fr := pickFrame(f, nodeFrames, i, depth)
+		fn := fr.method
+		if fr.class != "" {
+			fn = fr.class + "." + fn
+		}
+		if f.IntN(3) == 0 {
+			fn = "async " + fn
+		}
+		lines = append(lines, fmt.Sprintf("    at %s (%s/%s:%d:%d)", fn, fr.pkg, fr.file, f.Number(5, 400), f.Number(1, 40)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+var dotnetFrames = []frame{
+	{"Shop.Orders", "OrderService", "ProcessAsync(Order order, CancellationToken cancellationToken)", "/src/Shop.Orders/OrderService.cs"},
+	{"Shop.Orders", "OrdersController", "Create(CreateOrderRequest request)", "/src/Shop.Orders/OrdersController.cs"},
+	{"Shop.Payments", "PaymentClient", "AuthorizeAsync(Guid customerId, Decimal amount)", "/src/Shop.Payments/PaymentClient.cs"},
+	{"Shop.Data", "CartRepository", "GetAsync(Guid id)", "/src/Shop.Data/CartRepository.cs"},
+	{"Microsoft.AspNetCore.Mvc.Infrastructure", "ControllerActionInvoker", "InvokeActionMethodAsync()", ""},
+	{"Microsoft.AspNetCore.Mvc.Infrastructure", "ResourceInvoker", "InvokeFilterPipelineAsync()", ""},
+	{"Microsoft.AspNetCore.Routing", "EndpointMiddleware", "Invoke(HttpContext httpContext)", ""},
+	{"Microsoft.EntityFrameworkCore.Query.Internal", "SingleQueryingEnumerable`1.AsyncEnumerator", "MoveNextAsync()", ""},
+}
+
+var dotnetExceptions = []func(f *gofakeit.Faker) string{
+	func(f *gofakeit.Faker) string {
+		return "System.NullReferenceException: Object reference not set to an instance of an object."
+	},
+	func(f *gofakeit.Faker) string {
+		return fmt.Sprintf("System.InvalidOperationException: Order %d has already been paid.", f.Number(1, 100000))
+	},
+	func(f *gofakeit.Faker) string {
+		return "System.TimeoutException: The operation has timed out."
+	},
+	func(f *gofakeit.Faker) string {
+		return "Microsoft.Data.SqlClient.SqlException (0x80131904): Transaction (Process ID 57) was deadlocked on lock resources with another process and has been chosen as the deadlock victim."
+	},
+}
+
+func dotnetFrame(f *gofakeit.Faker, fr frame) string {
+	line := fmt.Sprintf("   at %s.%s.%s", fr.pkg, fr.class, fr.method)
+	if fr.file != "" {
+		line += fmt.Sprintf(" in %s:line %d", fr.file, f.Number(20, 500))
+	}
+	return line
+}
+
+func dotnetStackTrace(f *gofakeit.Faker, depth int) string {
+	// The inner exceptions come first in the message, and last in the frames.
+	exceptions := []string{dotnetExceptions[f.IntN(len(dotnetExceptions))](f)}
+	for inner := f.IntN(3); inner > 0; inner-- {
+		exceptions = append(exceptions, dotnetExceptions[f.IntN(len(dotnetExceptions))](f))
+	}
+	lines := []string{strings.Join(exceptions, " ---> ")}
+	for i := len(exceptions) - 1; i >= 0; i-- {
+		n := depth
+		if i > 0 {
+			n = f.Number(1, 4)
+		}
+		for j := 0; j < n; j++ {
+			lines = append(lines, dotnetFrame(f, pickFrame(f, dotnetFrames, j, n)))
+		}
+		if i > 0 {
+			lines = append(lines, "   --- End of inner exception stack trace ---")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+var rubyFrames = []frame{
+	{"app/models", "", "process", "order.rb"},
+	{"app/services", "", "call", "checkout.rb"},
+	{"app/controllers", "", "create", "orders_controller.rb"},
+	{"app/services", "", "charge!", "payment_gateway.rb"},
+	{"/usr/local/bundle/gems/actionpack-7.1.3/lib/action_controller/metal", "", "send_action", "basic_implicit_render.rb"},
+	{"/usr/local/bundle/gems/activesupport-7.1.3/lib/active_support", "", "run_callbacks", "callbacks.rb"},
+	{"/usr/local/bundle/gems/actionpack-7.1.3/lib/abstract_controller", "", "process_action", "base.rb"},
+	{"/usr/local/bundle/gems/rack-3.0.9/lib/rack", "", "call", "runtime.rb"},
+}
+
+var rubyErrors = []func(f *gofakeit.Faker) string{
+	func(f *gofakeit.Faker) string { return "undefined method `id' for nil:NilClass (NoMethodError)" },
+	func(f *gofakeit.Faker) string {
+		return fmt.Sprintf("Couldn't find Order with 'id'=%d (ActiveRecord::RecordNotFound)", f.Number(1, 100000))
+	},
+	func(f *gofakeit.Faker) string { return "execution expired (Net::ReadTimeout)" },
+	func(f *gofakeit.Faker) string { return "key not found: :customer_id (KeyError)" },
+}
+
+func rubyFrame(f *gofakeit.Faker, fr frame) string {
+	return fmt.Sprintf("%s/%s:%d:in `%s'", fr.pkg, fr.file, f.Number(5, 400), fr.method)
+}
+
+func rubyBacktrace(f *gofakeit.Faker, depth int) string {
+	// Ruby prints the error on the line of the innermost frame.
+	lines := []string{rubyFrame(f, pickFrame(f, rubyFrames, 0, depth)) + ": " + rubyErrors[f.IntN(len(rubyErrors))](f)}
+	for i := 1; i < depth; i++ {
+		lines = append(lines, "\tfrom "+rubyFrame(f, pickFrame(f, rubyFrames, i, depth)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// pickFrame picks the i-th of depth frames, innermost first. The first half of frames is the application, the second
+// half the framework, so that traces go from the code that failed down to the framework that called it.
+func pickFrame(f *gofakeit.Faker, frames []frame, i, depth int) frame {
+	half := len(frames) / 2
+	if i < depth/2 {
+		return frames[f.IntN(half)]
+	}
+	return frames[half+f.IntN(len(frames)-half)]
+}
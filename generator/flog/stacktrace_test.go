@@ -0,0 +1,73 @@
+package flog
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewStackTrace(t *testing.T) {
+	// Matches the first line, and every frame of the trace.
+	shapes := map[string][2]*regexp.Regexp{
+		"java":   {regexp.MustCompile(`^[a-z.]+\.[A-Za-z]+(Exception|Error)`), regexp.MustCompile(`^\tat \S+\(\S+\.java:\d+\)$`)},
+		"python": {regexp.MustCompile(`^Traceback \(most recent call last\):$`), regexp.MustCompile(`^  File "\S+\.py", line \d+, in \S+$`)},
+		"go":     {regexp.MustCompile(`^panic: `), regexp.MustCompile(`^\t/\S+\.go:\d+ \+0x[0-9a-f]+$`)},
+		"node":   {regexp.MustCompile(`^[A-Za-z]*Error: `), regexp.MustCompile(`^    at .+ \(\S+:\d+:\d+\)$`)},
+		"dotnet": {regexp.MustCompile(`^[A-Za-z.]+Exception( \(0x[0-9A-F]+\))?: `), regexp.MustCompile(`^   at \S+`)},
+		"ruby":   {regexp.MustCompile(`^\S+\.rb:\d+:in .+ \([A-Za-z:]+\)$`), regexp.MustCompile(`^\tfrom \S+\.rb:\d+:in `)},
+	}
+	f := gofakeit.New(1)
+	for _, runtime := range Runtimes {
+		t.Run(runtime, func(t *testing.T) {
+			depths := map[int]bool{}
+			for i := 0; i < 20; i++ {
+				lines := strings.Split(NewStackTrace(f, runtime), "\n")
+				assert.Regexp(t, shapes[runtime][0], lines[0])
+				frames := 0
+				for _, line := range lines {
+					if shapes[runtime][1].MatchString(line) {
+						frames++
+					}
+				}
+				assert.GreaterOrEqual(t, frames, 3, lines)
+				depths[frames] = true
+			}
+			assert.Greater(t, len(depths), 1, "the depth varies")
+		})
+	}
+
+	assert.Equal(t, NewStackTrace(gofakeit.New(7), "java"), NewStackTrace(gofakeit.New(7), "java"), "a seeded faker draws the same trace")
+}
+
+func TestNewStackTraceCauses(t *testing.T) {
+	f := gofakeit.New(1)
+	var java, dotnet, python, goroutines bool
+	for i := 0; i < 50; i++ {
+		java = java || strings.Contains(NewStackTrace(f, "java"), "\nCaused by: ")
+		dotnet = dotnet || strings.Contains(NewStackTrace(f, "dotnet"), "--- End of inner exception stack trace ---")
+		python = python || strings.Contains(NewStackTrace(f, "python"), "During handling of the above exception")
+		goroutines = goroutines || strings.Count(NewStackTrace(f, "go"), "\ngoroutine ") > 1
+	}
+	assert.True(t, java, "java traces chain their causes")
+	assert.True(t, dotnet, ".NET traces chain their inner exceptions")
+	assert.True(t, python, "python tracebacks chain the exceptions raised while handling another")
+	assert.True(t, goroutines, "go panics dump the other goroutines")
+}
+
+func TestNewRuntimeLog(t *testing.T) {
+	f := gofakeit.New(1)
+	ts := time.Date(2024, 3, 5, 7, 8, 9, 0, time.UTC)
+	for _, runtime := range Runtimes {
+		info := NewRuntimeLog(f, ts, runtime, "info")
+		assert.NotContains(t, info, "\n", runtime)
+		assert.Contains(t, strings.ToLower(info), "inf", runtime)
+
+		lines := strings.Split(NewRuntimeLog(f, ts, runtime, "error"), "\n")
+		assert.Greater(t, len(lines), 3, runtime)
+	}
+	assert.True(t, strings.HasPrefix(NewRuntimeLog(f, ts, "go", "error"), "panic: "), "the runtime writes go panics")
+}
@@ -0,0 +1,142 @@
+package flog
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/brianvoe/gofakeit/v7"
+)
+
+// SDParam is a PARAM-NAME="PARAM-VALUE" pair of an RFC5424 SD-ELEMENT.
+type SDParam struct {
+	Name  string
+	Value string
+}
+
+// SDElement is an RFC5424 SD-ELEMENT: an SD-ID, such as origin or exampleSDID@32473, with its params.
+type SDElement struct {
+	ID     string
+	Params []SDParam
+}
+
+// StructuredData is the STRUCTURED-DATA part of an RFC5424 message.
+type StructuredData []SDElement
+
+var sdValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+// EscapeSDValue escapes the characters that must be escaped in a PARAM-VALUE: '"', '\' and ']'.
+func EscapeSDValue(value string) string {
+	return sdValueEscaper.Replace(value)
+}
+
+// String formats e as [ID name="value"...].
+func (e SDElement) String() string {
+	var sb strings.Builder
+	sb.WriteString("[")
+	sb.WriteString(e.ID)
+	for _, param := range e.Params {
+		sb.WriteString(" ")
+		sb.WriteString(param.Name)
+		sb.WriteString(`="`)
+		sb.WriteString(EscapeSDValue(param.Value))
+		sb.WriteString(`"`)
+	}
+	sb.WriteString("]")
+	return sb.String()
+}
+
+// String formats the elements, or returns the NILVALUE "-" without any.
+func (sd StructuredData) String() string {
+	if len(sd) == 0 {
+		return "-"
+	}
+	var sb strings.Builder
+	for _, e := range sd {
+		sb.WriteString(e.String())
+	}
+	return sb.String()
+}
+
+// syslogMsgIDs are the MSGIDs of common daemons, "-" is the NILVALUE used by most applications.
+var syslogMsgIDs = []string{"-", "-", "-", "TCPIN", "TCPOUT", "AUDIT", "LOGIN", "LOGOUT", "SESSION", "CRON", "DHCPACK", "CONFIG", "ALERT"}
+
+// RandMsgID returns a random RFC5424 MSGID.
+func RandMsgID(f *gofakeit.Faker) string {
+	return syslogMsgIDs[f.IntN(len(syslogMsgIDs))]
+}
+
+// RandProcID returns a random RFC5424 PROCID: mostly a pid, sometimes a named worker or the NILVALUE.
+func RandProcID(f *gofakeit.Faker) string {
+	switch f.IntN(10) {
+	case 0:
+		return "-"
+	case 1:
+		return "worker-" + strconv.Itoa(f.Number(1, 16))
+	default:
+		return strconv.Itoa(f.Number(1, 65535))
+	}
+}
+
+// RandStructuredData returns a random subset of the IANA registered SD-IDs and of custom enterprise ones, with
+// values that need escaping. A quarter of the messages have none.
+func RandStructuredData(f *gofakeit.Faker) StructuredData {
+	var sd StructuredData
+	if f.IntN(4) == 0 {
+		return sd
+	}
+	if f.Bool() {
+		synced := f.Bool()
+		e := SDElement{ID: "timeQuality", Params: []SDParam{
+			{Name: "tzKnown", Value: "1"},
+			{Name: "isSynced", Value: boolValue(synced)},
+		}}
+		if synced {
+			e.Params = append(e.Params, SDParam{Name: "syncAccuracy", Value: strconv.Itoa(f.Number(1000, 500000))})
+		}
+		sd = append(sd, e)
+	}
+	if f.Bool() {
+		sd = append(sd, SDElement{ID: "origin", Params: []SDParam{
+			{Name: "ip", Value: f.IPv4Address()},
+			{Name: "enterpriseId", Value: "32473"},
+			{Name: "software", Value: f.AppName()},
+			{Name: "swVersion", Value: f.AppVersion()},
+		}})
+	}
+	if f.Bool() {
+		sd = append(sd, SDElement{ID: "meta", Params: []SDParam{
+			{Name: "sequenceId", Value: strconv.Itoa(f.Number(1, 2147483647))},
+			{Name: "sysUpTime", Value: strconv.Itoa(f.Number(100, 100000000))},
+			{Name: "language", Value: f.RandomString([]string{"en-US", "en-GB", "de-DE", "fr-FR", "ja-JP"})},
+		}})
+	}
+	switch f.IntN(3) {
+	case 0:
+		sd = append(sd, SDElement{ID: "exampleSDID@32473", Params: []SDParam{
+			{Name: "iut", Value: strconv.Itoa(f.Number(1, 9))},
+			{Name: "eventSource", Value: f.RandomString([]string{"Application", "Security", "System"})},
+			{Name: "eventID", Value: strconv.Itoa(f.Number(1000, 9999))},
+		}})
+	case 1:
+		sd = append(sd, SDElement{ID: "audit@41058", Params: []SDParam{
+			{Name: "user", Value: strings.ToLower(f.Username())},
+			{Name: "action", Value: f.RandomString([]string{"login", "logout", "sudo", "chmod", "delete"})},
+			{Name: "result", Value: f.RandomString([]string{"success", "failure"})},
+			{Name: "reason", Value: `"` + f.HackerPhrase() + `" [code ` + strconv.Itoa(f.Number(1, 99)) + `]`},
+		}})
+	case 2:
+		sd = append(sd, SDElement{ID: "app@53595", Params: []SDParam{
+			{Name: "path", Value: `C:\Program Files\` + f.AppName() + `\` + f.Word() + ".exe"},
+			{Name: "requestId", Value: f.UUID()},
+			{Name: "durationMs", Value: strconv.Itoa(f.Number(1, 5000))},
+		}})
+	}
+	return sd
+}
+
+func boolValue(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
@@ -0,0 +1,32 @@
+package flog
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStructuredDataString(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal("-", StructuredData(nil).String(), "no elements is the NILVALUE")
+	sd := StructuredData{
+		{ID: "timeQuality", Params: []SDParam{{Name: "tzKnown", Value: "1"}}},
+		{ID: "exampleSDID@32473", Params: []SDParam{{Name: "reason", Value: `"quoted" [x] C:\tmp`}}},
+	}
+	a.Equal(`[timeQuality tzKnown="1"][exampleSDID@32473 reason="\"quoted\" [x\] C:\\tmp"]`, sd.String())
+}
+
+// rfc5424 matches HEADER SP STRUCTURED-DATA SP MSG, with the escaped PARAM-VALUEs.
+var rfc5424 = regexp.MustCompile(`^<\d{1,3}>1 \S+ \S+ \S+ \S{1,128} \S{1,32} (-|(\[[^ \]=\"]+( [^ \]=\"]+="([^"\\\]]|\\.)*")*\])+) .+$`)
+
+func TestNewRFC5424Log(t *testing.T) {
+	f := gofakeit.New(1)
+	for i := 0; i < 200; i++ {
+		line := NewRFC5424Log(f, time.Now())
+		assert.Regexp(t, rfc5424, line)
+	}
+}
@@ -7,4 +7,11 @@ const (
 	RFC3164     = "Jan 02 15:04:05"
 	RFC5424     = "2006-01-02T15:04:05.000Z"
 	CommonLog   = "02/Jan/2006:15:04:05 -0700"
+	NginxError  = "2006/01/02 15:04:05"
+	Postgres    = "2006-01-02 15:04:05.000 MST"
+	MySQL       = "2006-01-02T15:04:05.000000Z07:00"
+	Redis       = "02 Jan 2006 15:04:05.000"
+	Kafka       = "2006-01-02 15:04:05,000"
+	// Windows has the 100ns precision of Windows.
+	Windows = "2006-01-02T15:04:05.0000000Z"
 )
@@ -0,0 +1,400 @@
+package flog
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v7"
+)
+
+// WindowsChannels are the event logs NewWindowsEvent writes to.
+var WindowsChannels = []string{"Security", "System", "Application"}
+
+// Windows event levels.
+const (
+	WindowsLogAlways   = 0
+	WindowsCritical    = 1
+	WindowsError       = 2
+	WindowsWarning     = 3
+	WindowsInformation = 4
+)
+
+var windowsLevelTexts = map[int]string{
+	WindowsLogAlways:   "Information",
+	WindowsCritical:    "Critical",
+	WindowsError:       "Error",
+	WindowsWarning:     "Warning",
+	WindowsInformation: "Information",
+}
+
+// Keywords of the events, with their rendered text.
+const (
+	auditSuccess uint64 = 0x8020000000000000
+	auditFailure uint64 = 0x8010000000000000
+	classic      uint64 = 0x8080000000000000
+)
+
+var windowsKeywordTexts = map[uint64]string{auditSuccess: "Audit Success", auditFailure: "Audit Failure", classic: "Classic"}
+
+// WindowsEventData is a named value of the EventData of an event.
+type WindowsEventData struct {
+	Name, Value string
+}
+
+// WindowsEvent is an event of the Windows Event Log.
+type WindowsEvent struct {
+	Provider, ProviderGUID string
+	EventID, Version       int
+	Level, Task, Opcode    int
+	TaskText               string
+	Keywords               uint64
+	TimeCreated            time.Time
+	RecordID               int
+	ProcessID, ThreadID    int
+	Channel, Computer      string
+	// UserID is the SID of the user the event was logged as, if any.
+	UserID  string
+	Data    []WindowsEventData
+	Message string
+}
+
+// windowsEventType describes the events with an id. Message holds {Name} placeholders replaced with the data.
+type windowsEventType struct {
+	channel, provider, guid string
+	id, version, level      int
+	task                    int
+	taskText                string
+	keywords                uint64
+	userID                  string
+	// severity is the generator level the event is written at.
+	severity string
+	data     func(f *gofakeit.Faker, computer string) []WindowsEventData
+	message  string
+}
+
+const (
+	securityAuditing = "Microsoft-Windows-Security-Auditing"
+	securityGUID     = "{54849625-5478-4994-a5ba-3e3b0328c30d}"
+	scm              = "Service Control Manager"
+	scmGUID          = "{555908d1-a6d7-4695-8e1e-26931d2012f4}"
+)
+
+var windowsUsers = []string{"jdoe", "asmith", "svc_backup", "svc_sql", "administrator", "mlopez"}
+
+var windowsServices = []string{"Windows Update", "Print Spooler", "Background Intelligent Transfer Service", "Windows Defender Antivirus Service", "Grafana Alloy", "SQL Server (MSSQLSERVER)"}
+
+var windowsApps = []string{"w3wp.exe", "sqlservr.exe", "explorer.exe", "OrderService.exe", "chrome.exe"}
+
+func windowsSID(f *gofakeit.Faker) string {
+	return fmt.Sprintf("S-1-5-21-%d-%d-%d-%d", f.Number(1000000000, 2000000000), f.Number(1000000000, 2000000000), f.Number(1000000000, 2000000000), f.Number(1000, 5000))
+}
+
+func logonData(f *gofakeit.Faker, computer string) []WindowsEventData {
+	domain := strings.ToUpper(strings.SplitN(computer, ".", 2)[0])
+	return []WindowsEventData{
+		{"SubjectUserSid", "S-1-5-18"},
+		{"SubjectUserName", domain + "$"},
+		{"SubjectDomainName", "CORP"},
+		{"SubjectLogonId", "0x3e7"},
+		{"TargetUserSid", windowsSID(f)},
+		{"TargetUserName", windowsUsers[f.IntN(len(windowsUsers))]},
+		{"TargetDomainName", "CORP"},
+		{"TargetLogonId", fmt.Sprintf("0x%x", f.Number(0x100000, 0xffffff))},
+		{"LogonType", f.RandomString([]string{"2", "3", "3", "3", "10"})},
+		{"LogonProcessName", "NtLmSsp "},
+		{"AuthenticationPackageName", f.RandomString([]string{"NTLM", "Kerberos", "Negotiate"})},
+		{"WorkstationName", fmt.Sprintf("WKS-%03d", f.Number(1, 300))},
+		{"IpAddress", FakeIP(f)},
+		{"IpPort", fmt.Sprint(f.Number(49152, 65535))},
+	}
+}
+
+var windowsEventTypes = []windowsEventType{
+	{
+		channel: "Security", provider: securityAuditing, guid: securityGUID,
+		id: 4624, version: 2, level: WindowsLogAlways, task: 12544, taskText: "Logon", keywords: auditSuccess,
+		severity: "info", data: logonData,
+		message: "An account was successfully logged on.\r\n\r\nSubject:\r\n\tSecurity ID:\t\t{SubjectUserSid}\r\n\tAccount Name:\t\t{SubjectUserName}\r\n\tAccount Domain:\t\t{SubjectDomainName}\r\n\tLogon ID:\t\t{SubjectLogonId}\r\n\r\nLogon Information:\r\n\tLogon Type:\t\t{LogonType}\r\n\r\nNew Logon:\r\n\tSecurity ID:\t\t{TargetUserSid}\r\n\tAccount Name:\t\t{TargetUserName}\r\n\tAccount Domain:\t\t{TargetDomainName}\r\n\tLogon ID:\t\t{TargetLogonId}\r\n\r\nNetwork Information:\r\n\tWorkstation Name:\t{WorkstationName}\r\n\tSource Network Address:\t{IpAddress}\r\n\tSource Port:\t\t{IpPort}",
+	},
+	{
+		channel: "Security", provider: securityAuditing, guid: securityGUID,
+		id: 4625, version: 0, level: WindowsLogAlways, task: 12544, taskText: "Logon", keywords: auditFailure,
+		severity: "warn",
+		data: func(f *gofakeit.Faker, computer string) []WindowsEventData {
+			return append(logonData(f, computer),
+				WindowsEventData{"Status", "0xc000006d"},
+				WindowsEventData{"SubStatus", f.RandomString([]string{"0xc000006a", "0xc0000064", "0xc0000234"})},
+				WindowsEventData{"FailureReason", "%%2313"},
+			)
+		},
+		message: "An account failed to log on.\r\n\r\nSubject:\r\n\tSecurity ID:\t\t{SubjectUserSid}\r\n\tAccount Name:\t\t{SubjectUserName}\r\n\r\nLogon Type:\t\t\t{LogonType}\r\n\r\nAccount For Which Logon Failed:\r\n\tAccount Name:\t\t{TargetUserName}\r\n\tAccount Domain:\t\t{TargetDomainName}\r\n\r\nFailure Information:\r\n\tFailure Reason:\t\tUnknown user name or bad password.\r\n\tStatus:\t\t\t{Status}\r\n\tSub Status:\t\t{SubStatus}\r\n\r\nNetwork Information:\r\n\tWorkstation Name:\t{WorkstationName}\r\n\tSource Network Address:\t{IpAddress}\r\n\tSource Port:\t\t{IpPort}",
+	},
+	{
+		channel: "Security", provider: securityAuditing, guid: securityGUID,
+		id: 4634, version: 0, level: WindowsLogAlways, task: 12545, taskText: "Logoff", keywords: auditSuccess,
+		severity: "info",
+		data: func(f *gofakeit.Faker, _ string) []WindowsEventData {
+			return []WindowsEventData{
+				{"TargetUserSid", windowsSID(f)},
+				{"TargetUserName", windowsUsers[f.IntN(len(windowsUsers))]},
+				{"TargetDomainName", "CORP"},
+				{"TargetLogonId", fmt.Sprintf("0x%x", f.Number(0x100000, 0xffffff))},
+				{"LogonType", "3"},
+			}
+		},
+		message: "An account was logged off.\r\n\r\nSubject:\r\n\tSecurity ID:\t\t{TargetUserSid}\r\n\tAccount Name:\t\t{TargetUserName}\r\n\tAccount Domain:\t\t{TargetDomainName}\r\n\tLogon ID:\t\t{TargetLogonId}\r\n\r\nLogon Type:\t\t\t{LogonType}",
+	},
+	{
+		channel: "Security", provider: securityAuditing, guid: securityGUID,
+		id: 4688, version: 2, level: WindowsLogAlways, task: 13312, taskText: "Process Creation", keywords: auditSuccess,
+		severity: "info",
+		data: func(f *gofakeit.Faker, _ string) []WindowsEventData {
+			app := windowsApps[f.IntN(len(windowsApps))]
+			return []WindowsEventData{
+				{"SubjectUserSid", windowsSID(f)},
+				{"SubjectUserName", windowsUsers[f.IntN(len(windowsUsers))]},
+				{"SubjectDomainName", "CORP"},
+				{"NewProcessId", fmt.Sprintf("0x%x", f.Number(0x100, 0xffff))},
+				{"NewProcessName", `C:\Program Files\Example\` + app},
+				{"TokenElevationType", "%%1936"},
+				{"ProcessId", fmt.Sprintf("0x%x", f.Number(0x100, 0xffff))},
+				{"CommandLine", fmt.Sprintf(`"C:\Program Files\Example\%s" --config C:\ProgramData\Example\%s.yaml`, app, strings.TrimSuffix(app, ".exe"))},
+			}
+		},
+		message: "A new process has been created.\r\n\r\nCreator Subject:\r\n\tSecurity ID:\t\t{SubjectUserSid}\r\n\tAccount Name:\t\t{SubjectUserName}\r\n\r\nProcess Information:\r\n\tNew Process ID:\t\t{NewProcessId}\r\n\tNew Process Name:\t{NewProcessName}\r\n\tCreator Process ID:\t{ProcessId}\r\n\tProcess Command Line:\t{CommandLine}",
+	},
+	{
+		channel: "System", provider: scm, guid: scmGUID,
+		id: 7036, level: WindowsInformation, keywords: classic, userID: "S-1-5-18",
+		severity: "info",
+		data: func(f *gofakeit.Faker, _ string) []WindowsEventData {
+			return []WindowsEventData{
+				{"param1", windowsServices[f.IntN(len(windowsServices))]},
+				{"param2", f.RandomString([]string{"running", "stopped"})},
+			}
+		},
+		message: "The {param1} service entered the {param2} state.",
+	},
+	{
+		channel: "System", provider: "EventLog",
+		id: 6013, level: WindowsInformation, keywords: classic,
+		severity: "info",
+		data: func(f *gofakeit.Faker, _ string) []WindowsEventData {
+			return []WindowsEventData{{"Uptime", fmt.Sprint(f.Number(3600, 10000000))}, {"TimeZone", "0 UTC"}}
+		},
+		message: "The system uptime is {Uptime} seconds.",
+	},
+	{
+		channel: "System", provider: "Microsoft-Windows-DNS-Client", guid: "{1c95126e-7eea-49a9-a3fe-a378b03ddb4d}",
+		id: 1014, level: WindowsWarning, task: 1014, keywords: 0x4000000000000000, userID: "S-1-5-20",
+		severity: "warn",
+		data: func(f *gofakeit.Faker, _ string) []WindowsEventData {
+			return []WindowsEventData{{"QueryName", f.DomainName()}, {"AddressLength", "128"}}
+		},
+		message: "Name resolution for the name {QueryName} timed out after none of the configured DNS servers responded.",
+	},
+	{
+		channel: "System", provider: scm, guid: scmGUID,
+		id: 7000, level: WindowsError, keywords: classic,
+		severity: "error",
+		data: func(f *gofakeit.Faker, _ string) []WindowsEventData {
+			return []WindowsEventData{
+				{"param1", windowsServices[f.IntN(len(windowsServices))]},
+				{"param2", "%%1053"},
+			}
+		},
+		message: "The {param1} service failed to start due to the following error: \r\nThe service did not respond to the start or control request in a timely fashion.",
+	},
+	{
+		channel: "System", provider: "Microsoft-Windows-Kernel-Power", guid: "{331c3b3a-2005-44c2-ac5e-77220c37d6b4}",
+		id: 41, version: 9, level: WindowsCritical, task: 63, keywords: 0x8000400000000002, userID: "S-1-5-18",
+		severity: "error",
+		data: func(f *gofakeit.Faker, _ string) []WindowsEventData {
+			return []WindowsEventData{{"BugcheckCode", "0"}, {"PowerButtonTimestamp", "0"}, {"SleepInProgress", "0"}}
+		},
+		message: "The system has rebooted without cleanly shutting down first. This error could be caused if the system stopped responding, crashed, or lost power unexpectedly.",
+	},
+	{
+		channel: "Application", provider: "Application Error",
+		id: 1000, level: WindowsError, task: 100, keywords: classic,
+		severity: "error",
+		data: func(f *gofakeit.Faker, _ string) []WindowsEventData {
+			return []WindowsEventData{
+				{"AppName", windowsApps[f.IntN(len(windowsApps))]},
+				{"AppVersion", fmt.Sprintf("%d.%d.%d.%d", f.Number(1, 10), f.Number(0, 9), f.Number(0, 20000), f.Number(0, 999))},
+				{"ModuleName", f.RandomString([]string{"ntdll.dll", "KERNELBASE.dll", "clr.dll", "ucrtbase.dll"})},
+				{"ExceptionCode", f.RandomString([]string{"c0000005", "c0000409", "e0434352"})},
+				{"FaultingOffset", fmt.Sprintf("%016x", f.Number(0x1000, 0xfffff))},
+			}
+		},
+		message: "Faulting application name: {AppName}, version: {AppVersion}\r\nFaulting module name: {ModuleName}\r\nException code: 0x{ExceptionCode}\r\nFault offset: 0x{FaultingOffset}",
+	},
+	{
+		channel: "Application", provider: ".NET Runtime",
+		id: 1026, level: WindowsError, keywords: classic,
+		severity: "error",
+		data: func(f *gofakeit.Faker, _ string) []WindowsEventData {
+			return []WindowsEventData{{"Data", "Application: OrderService.exe\r\nCoreCLR Version: 8.0.224.6711\r\nDescription: The process was terminated due to an unhandled exception.\r\nException Info: System.NullReferenceException: Object reference not set to an instance of an object.\r\n   at Shop.Orders.OrderService.ProcessAsync(Order order)"}}
+		},
+		message: "{Data}",
+	},
+	{
+		channel: "Application", provider: "MsiInstaller",
+		id: 11707, level: WindowsInformation, keywords: classic, userID: "S-1-5-18",
+		severity: "info",
+		data: func(f *gofakeit.Faker, _ string) []WindowsEventData {
+			return []WindowsEventData{{"param1", f.RandomString([]string{"Grafana Alloy", "Microsoft Edge", "7-Zip 23.01 (x64)", "Datadog Agent"})}}
+		},
+		message: "Product: {param1} -- Installation completed successfully.",
+	},
+	{
+		channel: "Application", provider: "Microsoft-Windows-Security-SPP", guid: "{e23b33b0-c8c9-472c-a5f9-f2bdfea0f156}",
+		id: 16384, level: WindowsInformation, keywords: classic,
+		severity: "info",
+		data: func(f *gofakeit.Faker, _ string) []WindowsEventData {
+			return []WindowsEventData{{"RestartTime", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(f.Number(0, 8760)) * time.Hour).Format("2006-01-02T15:04:05Z")}}
+		},
+		message: "Successfully scheduled Software Protection service for re-start at {RestartTime}. Reason: RulesEngine.",
+	},
+	{
+		channel: "Application", provider: "ESENT",
+		id: 508, level: WindowsWarning, task: 1, keywords: classic,
+		severity: "warn",
+		data: func(f *gofakeit.Faker, _ string) []WindowsEventData {
+			return []WindowsEventData{{"Seconds", fmt.Sprint(f.Number(20, 120))}}
+		},
+		message: "svchost ({Seconds}) SRUJet: A request to write to the file \"C:\\Windows\\system32\\SRU\\SRU.log\" at offset 1048576 (0x0000000000100000) for 4096 (0x00001000) bytes succeeded, but took an abnormally long time ({Seconds} seconds) to be serviced by the OS.",
+	},
+}
+
+// NewWindowsEvent creates a random event logged at level by computer, recordID is its number in its channel.
+// Security events are audit events, logged at the LogAlways level whether they succeeded or failed.
+func NewWindowsEvent(f *gofakeit.Faker, t time.Time, level, computer string, recordID int) WindowsEvent {
+	var candidates []windowsEventType
+	for _, typ := range windowsEventTypes {
+		if typ.severity == level {
+			candidates = append(candidates, typ)
+		}
+	}
+	if len(candidates) == 0 {
+		return NewWindowsEvent(f, t, "info", computer, recordID)
+	}
+	typ := candidates[f.IntN(len(candidates))]
+
+	data := typ.data(f, computer)
+	message := typ.message
+	for _, d := range data {
+		message = strings.ReplaceAll(message, "{"+d.Name+"}", d.Value)
+	}
+	return WindowsEvent{
+		Provider:     typ.provider,
+		ProviderGUID: typ.guid,
+		EventID:      typ.id,
+		Version:      typ.version,
+		Level:        typ.level,
+		Task:         typ.task,
+		TaskText:     typ.taskText,
+		Keywords:     typ.keywords,
+		TimeCreated:  t,
+		RecordID:     recordID,
+		ProcessID:    f.Number(4, 9000),
+		ThreadID:     f.Number(4, 12000),
+		Channel:      typ.channel,
+		Computer:     computer,
+		UserID:       typ.userID,
+		Data:         data,
+		Message:      message,
+	}
+}
+
+// XML renders the event like the Event Viewer and wevtutil do, with its RenderingInfo.
+func (e WindowsEvent) XML() string {
+	var b strings.Builder
+	b.WriteString("<Event xmlns='http://schemas.microsoft.com/win/2004/08/events/event'><System>")
+	b.WriteString("<Provider Name='" + xmlEscape(e.Provider) + "'")
+	if e.ProviderGUID != "" {
+		b.WriteString(" Guid='" + e.ProviderGUID + "'")
+	}
+	fmt.Fprintf(&b, "/><EventID>%d</EventID><Version>%d</Version><Level>%d</Level><Task>%d</Task><Opcode>%d</Opcode><Keywords>0x%x</Keywords>",
+		e.EventID, e.Version, e.Level, e.Task, e.Opcode, e.Keywords)
+	fmt.Fprintf(&b, "<TimeCreated SystemTime='%s'/><EventRecordID>%d</EventRecordID><Correlation/><Execution ProcessID='%d' ThreadID='%d'/>",
+		e.TimeCreated.UTC().Format(Windows), e.RecordID, e.ProcessID, e.ThreadID)
+	b.WriteString("<Channel>" + e.Channel + "</Channel><Computer>" + xmlEscape(e.Computer) + "</Computer>")
+	if e.UserID != "" {
+		b.WriteString("<Security UserID='" + e.UserID + "'/>")
+	} else {
+		b.WriteString("<Security/>")
+	}
+	b.WriteString("</System><EventData>")
+	for _, d := range e.Data {
+		b.WriteString("<Data Name='" + d.Name + "'>" + xmlEscape(d.Value) + "</Data>")
+	}
+	b.WriteString("</EventData><RenderingInfo Culture='en-US'><Message>" + xmlEscape(e.Message) + "</Message>")
+	b.WriteString("<Level>" + windowsLevelTexts[e.Level] + "</Level><Task>" + xmlEscape(cmp.Or(e.TaskText, "None")) + "</Task><Opcode>Info</Opcode>")
+	b.WriteString("<Channel>" + e.Channel + "</Channel><Provider>" + xmlEscape(e.Provider) + "</Provider>")
+	b.WriteString("<Keywords><Keyword>" + windowsKeywordTexts[e.Keywords] + "</Keyword></Keywords></RenderingInfo></Event>")
+	return b.String()
+}
+
+// xmlEscape escapes s like Windows does, keeping the line breaks and tabs of messages.
+var xmlEscape = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;", "'", "&apos;").Replace
+
+// JSON renders the event like loki.source.windowsevent does, with the event data as an object of its named values.
+func (e WindowsEvent) JSON() string {
+	type execution struct {
+		ProcessID int `json:"processId"`
+		ThreadID  int `json:"threadId"`
+	}
+	type security struct {
+		UserID string `json:"userId"`
+	}
+	event := struct {
+		Source        string            `json:"source,omitempty"`
+		Channel       string            `json:"channel,omitempty"`
+		Computer      string            `json:"computer,omitempty"`
+		EventID       int               `json:"event_id,omitempty"`
+		Version       int               `json:"version,omitempty"`
+		Level         int               `json:"level,omitempty"`
+		Task          int               `json:"task,omitempty"`
+		Opcode        int               `json:"opCode,omitempty"`
+		LevelText     string            `json:"levelText,omitempty"`
+		TaskText      string            `json:"taskText,omitempty"`
+		OpcodeText    string            `json:"opCodeText,omitempty"`
+		Keywords      string            `json:"keywords,omitempty"`
+		TimeCreated   string            `json:"timeCreated,omitempty"`
+		EventRecordID int               `json:"eventRecordID,omitempty"`
+		Execution     *execution        `json:"execution,omitempty"`
+		Security      *security         `json:"security,omitempty"`
+		EventData     map[string]string `json:"event_data,omitempty"`
+		Message       string            `json:"message,omitempty"`
+	}{
+		Source:        e.Provider,
+		Channel:       e.Channel,
+		Computer:      e.Computer,
+		EventID:       e.EventID,
+		Version:       e.Version,
+		Level:         e.Level,
+		Task:          e.Task,
+		Opcode:        e.Opcode,
+		LevelText:     windowsLevelTexts[e.Level],
+		TaskText:      e.TaskText,
+		OpcodeText:    "Info",
+		Keywords:      windowsKeywordTexts[e.Keywords],
+		TimeCreated:   e.TimeCreated.UTC().Format(Windows),
+		EventRecordID: e.RecordID,
+		Execution:     &execution{e.ProcessID, e.ThreadID},
+		EventData:     map[string]string{},
+		Message:       e.Message,
+	}
+	if e.UserID != "" {
+		event.Security = &security{e.UserID}
+	}
+	for _, d := range e.Data {
+		event.EventData[d.Name] = d.Value
+	}
+	b, _ := json.Marshal(event)
+	return string(b)
+}
@@ -0,0 +1,95 @@
+package flog
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWindowsEvent(t *testing.T) {
+	f := gofakeit.New(1)
+	ts := time.Date(2024, 3, 5, 7, 8, 9, 123456700, time.UTC)
+	channels := map[string]bool{}
+	for _, level := range []string{levelDebug, levelInfo, levelWarn, levelError} {
+		for i := 0; i < 50; i++ {
+			event := NewWindowsEvent(f, ts, level, "DC01.corp.example.com", 42)
+			channels[event.Channel] = true
+			assert.NotContains(t, event.Message, "{", "every placeholder is replaced")
+
+			var j struct {
+				Source      string            `json:"source"`
+				Channel     string            `json:"channel"`
+				Computer    string            `json:"computer"`
+				EventID     int               `json:"event_id"`
+				Keywords    string            `json:"keywords"`
+				TimeCreated string            `json:"timeCreated"`
+				RecordID    int               `json:"eventRecordID"`
+				EventData   map[string]string `json:"event_data"`
+				Message     string            `json:"message"`
+			}
+			require.NoError(t, json.Unmarshal([]byte(event.JSON()), &j))
+			assert.Equal(t, event.Provider, j.Source)
+			assert.Equal(t, event.EventID, j.EventID)
+			assert.Equal(t, "DC01.corp.example.com", j.Computer)
+			assert.Equal(t, "2024-03-05T07:08:09.1234567Z", j.TimeCreated)
+			assert.Equal(t, 42, j.RecordID)
+			assert.Len(t, j.EventData, len(event.Data))
+			assert.Equal(t, event.Message, j.Message)
+			if j.Channel == "Security" {
+				assert.Contains(t, []string{"Audit Success", "Audit Failure"}, j.Keywords)
+			}
+
+			var x struct {
+				System struct {
+					EventID int `xml:"EventID"`
+					Channel string
+				}
+				EventData struct {
+					Data []struct {
+						Name  string `xml:"Name,attr"`
+						Value string `xml:",chardata"`
+					}
+				}
+				RenderingInfo struct {
+					Message string
+				}
+			}
+			line := event.XML()
+			require.NoError(t, xml.Unmarshal([]byte(line), &x), line)
+			assert.Equal(t, event.EventID, x.System.EventID)
+			assert.Equal(t, event.Channel, x.System.Channel)
+			// XML normalizes the \r\n line breaks of Windows.
+			crlf := strings.NewReplacer("\r\n", "\n")
+			require.Len(t, x.EventData.Data, len(event.Data))
+			for k, d := range x.EventData.Data {
+				assert.Equal(t, event.Data[k].Name, d.Name)
+				assert.Equal(t, crlf.Replace(event.Data[k].Value), d.Value)
+			}
+			assert.Equal(t, crlf.Replace(event.Message), x.RenderingInfo.Message)
+		}
+	}
+	for _, channel := range WindowsChannels {
+		assert.True(t, channels[channel], channel)
+	}
+}
+
+func TestWindowsEventLevels(t *testing.T) {
+	f := gofakeit.New(1)
+	for i := 0; i < 50; i++ {
+		event := NewWindowsEvent(f, time.Now(), levelError, "WIN-1", 1)
+		assert.Contains(t, []int{WindowsCritical, WindowsError}, event.Level)
+
+		event = NewWindowsEvent(f, time.Now(), levelWarn, "WIN-1", 1)
+		if event.Channel == "Security" {
+			assert.Equal(t, 4625, event.EventID, "failed logons are the warnings of the Security log")
+		} else {
+			assert.Equal(t, WindowsWarning, event.Level)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package flog
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// RotateOptions configures a RotatingWriter
+type RotateOptions struct {
+	// Gzip compresses every finished segment, the one being written stays plain so that it can be tailed.
+	Gzip bool
+	// SplitBy is the maximum number of lines, or of bytes with SplitBytes, of a segment. Zero never rotates.
+	SplitBy    int
+	SplitBytes bool
+	// Overwrite truncates the existing files, they are appended to and kept otherwise.
+	Overwrite bool
+}
+
+// RotatingWriter writes lines to a file, moving it to a numbered segment whenever it reaches the split size.
+type RotatingWriter struct {
+	path  string
+	opts  RotateOptions
+	file  *os.File
+	lines int
+	size  int
+	// count is the number of the next segment.
+	count int
+}
+
+// NewWriter returns the writer of a log output type: stdout, or log and gz files rotated at path.
+func NewWriter(logType, path string, opts RotateOptions) (io.WriteCloser, error) {
+	switch logType {
+	case "stdout":
+		return nopCloser{os.Stdout}, nil
+	case "log":
+		opts.Gzip = false
+		return NewRotatingWriter(path, opts)
+	case "gz":
+		opts.Gzip = true
+		return NewRotatingWriter(path, opts)
+	}
+	return nil, fmt.Errorf("%s is not a valid log type", logType)
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+// NewRotatingWriter opens path, creating its directory if needed.
+func NewRotatingWriter(path string, opts RotateOptions) (*RotatingWriter, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	w := &RotatingWriter{path: path, opts: opts, count: 1}
+	if opts.Overwrite {
+		// Do not mix the segments of a previous run with the new ones.
+		if err := w.removeSegments(); err != nil {
+			return nil, err
+		}
+	} else {
+		// Keep the segments of a previous run.
+		for w.exists(w.count) {
+			w.count++
+		}
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *RotatingWriter) exists(count int) bool {
+	segment := NewSplitFileName(w.path, count)
+	for _, name := range []string{segment, segment + ".gz"} {
+		if _, err := os.Stat(name); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// removeSegments removes the numbered segments, and the compressed file, left by a previous run.
+func (w *RotatingWriter) removeSegments() error {
+	for count := 1; w.exists(count); count++ {
+		segment := NewSplitFileName(w.path, count)
+		for _, name := range []string{segment, segment + ".gz"} {
+			if err := os.Remove(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+		}
+	}
+	if err := os.Remove(w.path + ".gz"); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (w *RotatingWriter) open() error {
+	flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if w.opts.Overwrite {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	}
+	file, err := os.OpenFile(w.path, flags, 0o644)
+	if err != nil {
+		return err
+	}
+	w.file = file
+	w.lines, w.size = 0, 0
+	if !w.opts.Overwrite {
+		// Carry on with the file left by a previous run.
+		w.lines, w.size, err = countLines(w.path)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// countLines returns the number of lines and bytes of the file at path, reading it in chunks.
+func countLines(path string) (lines, size int, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := f.Read(buf)
+		lines += bytes.Count(buf[:n], []byte("\n"))
+		size += n
+		if errors.Is(err, io.EOF) {
+			return lines, size, nil
+		}
+		if err != nil {
+			return lines, size, err
+		}
+	}
+}
+
+// Write writes p, expected to hold whole lines, and rotates the file once it reached the split size.
+func (w *RotatingWriter) Write(p []byte) (int, error) {
+	n, err := w.file.Write(p)
+	w.lines += strings.Count(string(p[:n]), "\n")
+	w.size += n
+	if err != nil {
+		return n, err
+	}
+	if w.full() {
+		return n, w.rotate()
+	}
+	return n, nil
+}
+
+func (w *RotatingWriter) full() bool {
+	if w.opts.SplitBy <= 0 {
+		return false
+	}
+	if w.opts.SplitBytes {
+		return w.size >= w.opts.SplitBy
+	}
+	return w.lines >= w.opts.SplitBy
+}
+
+// rotate moves the current file to the next segment, and starts a new one.
+func (w *RotatingWriter) rotate() error {
+	if err := w.finish(NewSplitFileName(w.path, w.count)); err != nil {
+		return err
+	}
+	w.count++
+	return w.open()
+}
+
+// finish closes the current file and moves it to segment, compressing it if needed.
+func (w *RotatingWriter) finish(segment string) error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	if segment != w.path {
+		if err := os.Rename(w.path, segment); err != nil {
+			return err
+		}
+	}
+	if w.opts.Gzip {
+		return gzipFile(segment)
+	}
+	return nil
+}
+
+// Close closes the file being written. With Gzip, it is compressed like the other segments.
+func (w *RotatingWriter) Close() error {
+	if w.opts.Gzip && !w.opts.Overwrite {
+		if _, err := os.Stat(w.path + ".gz"); err == nil {
+			// Keep the file compressed by a previous run.
+			return w.finish(NewSplitFileName(w.path, w.count))
+		}
+	}
+	return w.finish(w.path)
+}
+
+// gzipFile replaces path with path.gz.
+func gzipFile(path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(path + ".gz")
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(out)
+	_, err = io.Copy(gz, in)
+	err = errors.Join(err, gz.Close(), out.Close())
+	if err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// NewSplitFileName creates a new file path with split count
+func NewSplitFileName(path string, count int) string {
+	logFileNameExt := filepath.Ext(path)
+	pathWithoutExt := strings.TrimSuffix(path, logFileNameExt)
+	return pathWithoutExt + strconv.Itoa(count) + logFileNameExt
+}
@@ -0,0 +1,101 @@
+package flog
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeLines(t *testing.T, w io.Writer, n int) {
+	for i := 0; i < n; i++ {
+		_, err := w.Write([]byte("line\n"))
+		require.NoError(t, err)
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func readGzip(t *testing.T, path string) string {
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	require.NoError(t, err)
+	data, err := io.ReadAll(gz)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestRotatingWriterLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	w, err := NewWriter("log", path, RotateOptions{SplitBy: 2})
+	require.NoError(t, err)
+	writeLines(t, w, 5)
+	require.NoError(t, w.Close())
+
+	assert.Equal(t, "line\nline\n", readFile(t, NewSplitFileName(path, 1)))
+	assert.Equal(t, "line\nline\n", readFile(t, NewSplitFileName(path, 2)))
+	assert.Equal(t, "line\n", readFile(t, path))
+}
+
+func TestRotatingWriterBytesGzip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	w, err := NewWriter("gz", path, RotateOptions{SplitBy: 10, SplitBytes: true})
+	require.NoError(t, err)
+	writeLines(t, w, 3)
+	require.NoError(t, w.Close())
+
+	assert.Equal(t, "line\nline\n", readGzip(t, NewSplitFileName(path, 1)+".gz"))
+	assert.Equal(t, "line\n", readGzip(t, path+".gz"))
+	assert.NoFileExists(t, path)
+}
+
+func TestRotatingWriterOverwrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	for i := 0; i < 2; i++ {
+		w, err := NewRotatingWriter(path, RotateOptions{SplitBy: 2})
+		require.NoError(t, err)
+		writeLines(t, w, 3)
+		require.NoError(t, w.Close())
+	}
+	assert.Equal(t, "line\nline\n", readFile(t, NewSplitFileName(path, 1)), "the segments of the first run are kept")
+	assert.Equal(t, "line\nline\n", readFile(t, NewSplitFileName(path, 2)), "the second run appends to the last file")
+	assert.Equal(t, "line\nline\n", readFile(t, NewSplitFileName(path, 3)))
+	assert.Empty(t, readFile(t, path))
+
+	w, err := NewRotatingWriter(path, RotateOptions{SplitBy: 2, Overwrite: true})
+	require.NoError(t, err)
+	writeLines(t, w, 1)
+	require.NoError(t, w.Close())
+	assert.Equal(t, "line\n", readFile(t, path))
+	for count := 1; count <= 3; count++ {
+		assert.NoFileExists(t, NewSplitFileName(path, count), "the segments of the previous runs are removed")
+	}
+}
+
+func TestCountLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	// Larger than a read, with a last line not terminated.
+	data := strings.Repeat("a longer line\n", 10000) + "partial"
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o644))
+
+	lines, size, err := countLines(path)
+	require.NoError(t, err)
+	assert.Equal(t, 10000, lines)
+	assert.Equal(t, len(data), size)
+}
+
+func TestNewWriterInvalid(t *testing.T) {
+	_, err := NewWriter("xml", "", RotateOptions{})
+	assert.Error(t, err)
+}
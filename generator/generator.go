@@ -3,157 +3,141 @@ package main
 import (
 	"context"
 	"fmt"
-	"math/rand"
+	"math"
+	"strings"
+	"sync"
 	"time"
 
-	"github.com/brianvoe/gofakeit"
+	"github.com/brianvoe/gofakeit/v7"
 	"github.com/grafana/explore-logs/generator/flog"
 	"github.com/grafana/explore-logs/generator/log"
 	"github.com/grafana/loki/pkg/push"
 	"github.com/prometheus/common/model"
 )
 
-type LogGenerator func(ctx context.Context, logger *log.AppLogger, metadata push.LabelsAdapter)
+// Stream is a single pod stream a LogGenerator writes to.
+type Stream struct {
+	Logger   *log.AppLogger
+	Metadata push.LabelsAdapter
+	// Rand is the stream's own source of randomness, forked for every goroutine started with Go.
+	Rand   *gofakeit.Faker
+	Clocks ClockSource
+	// Rate is the target entries per second of the stream, counting every line of split multi-line entries.
+	Rate float64
+	// Running tracks the goroutines started with Go, so that shutdown can wait for their last line.
+	Running *sync.WaitGroup
+	// Incidents are the incidents affecting the stream.
+	Incidents Incidents
+	// SplitLines writes the lines of multi-line entries, such as stack traces, as entries of their own.
+	SplitLines bool
+}
 
-var generators = map[model.LabelValue]map[model.LabelValue]LogGenerator{
-	"gateway": {
-		"apache": func(ctx context.Context, logger *log.AppLogger, metadata push.LabelsAdapter) {
-			go func() {
-				for ctx.Err() == nil {
-					level := log.RandLevel()
-					t := time.Now()
-					logger.LogWithMetadata(level, t, flog.NewApacheCommonLog(t, log.RandURI(), statusFromLevel(level)), metadata)
-					time.Sleep(time.Duration(rand.Intn(5000)) * time.Millisecond)
-				}
-			}()
-		},
-		"httpd": func(ctx context.Context, logger *log.AppLogger, metadata push.LabelsAdapter) {
-			go func() {
-				for ctx.Err() == nil {
-					level := log.RandLevel()
-					t := time.Now()
-					logger.LogWithMetadata(level, t, flog.NewApacheCombinedLog(t, log.RandURI(), statusFromLevel(level)), metadata)
-					time.Sleep(time.Duration(rand.Intn(5000)) * time.Millisecond)
-				}
-			}()
-		},
-		"nginx": func(ctx context.Context, logger *log.AppLogger, metadata push.LabelsAdapter) {
-			go func() {
-				for ctx.Err() == nil {
-					level := log.RandLevel()
-					t := time.Now()
-					logger.LogWithMetadata(level, t, flog.NewCommonLogFormat(t, log.RandURI(), statusFromLevel(level)), metadata)
-					time.Sleep(time.Duration(rand.Intn(5000)) * time.Millisecond)
-				}
-			}()
-		},
-		"nginx-json": func(ctx context.Context, logger *log.AppLogger, metadata push.LabelsAdapter) {
-			go func() {
-				for ctx.Err() == nil {
-					level := log.RandLevel()
-					t := time.Now()
-					logger.LogWithMetadata(level, t, flog.NewJSONLogFormat(t, log.RandURI(), statusFromLevel(level)), metadata)
-					time.Sleep(time.Duration(rand.Intn(5000)) * time.Millisecond)
-				}
-			}()
-		},
-		"nginx-json-mixed": func(ctx context.Context, logger *log.AppLogger, metadata push.LabelsAdapter) {
-			go func() {
+// Go runs loop in its own goroutine, with its own faker and clock.
+func (s *Stream) Go(loop func(f *gofakeit.Faker, clock Clock)) {
+	f := log.Fork(s.Rand)
+	clock, release := s.Clocks()
+	s.Running.Add(1)
+	go func() {
+		defer s.Running.Done()
+		defer release()
+		loop(f, clock)
+	}()
+}
+
+// pause returns the wait after the entries written at t. Pauses are exponentially distributed for every entry, so
+// that the entries of the stream follow a Poisson process at the stream's rate. Dropped lines count as an entry.
+func (s *Stream) pause(f *gofakeit.Faker, t time.Time, entries int) time.Duration {
+	var d float64
+	for range max(entries, 1) {
+		d += -math.Log(1-f.Float64()) / s.Rate
+	}
+	return s.Incidents.pause(t, time.Duration(d*float64(time.Second)))
+}
+
+// log writes a line, unless an ongoing incident drops or rewrites it, and returns the number of entries written.
+func (s *Stream) log(f *gofakeit.Faker, level model.LabelValue, t time.Time, line string, metadata push.LabelsAdapter) int {
+	level, line, ok := s.Incidents.apply(f, t, level, line)
+	if !ok {
+		return 0
+	}
+	if !s.SplitLines {
+		s.Logger.LogWithMetadata(level, t, line, metadata)
+		return 1
+	}
+	entries := 0
+	for _, l := range strings.Split(line, "\n") {
+		// Like agents reading the lines one by one, empty lines are skipped.
+		if l != "" {
+			s.Logger.LogWithMetadata(level, t, l, metadata)
+			entries++
+		}
+	}
+	return entries
+}
+
+type LogGenerator func(ctx context.Context, stream *Stream)
+
+// formats maps the format of a scenario service to the LogGenerator producing its lines.
+var formats = map[string]func(svc ServiceConfig) LogGenerator{
+	"apache_common": lineFormat(func(f *gofakeit.Faker, level model.LabelValue, t time.Time) string {
+		return flog.NewApacheCommonLog(f, t, log.RandURI(f), statusFromLevel(level))
+	}),
+	"apache_combined": lineFormat(func(f *gofakeit.Faker, level model.LabelValue, t time.Time) string {
+		return flog.NewApacheCombinedLog(f, t, log.RandURI(f), statusFromLevel(level))
+	}),
+	"common_log": lineFormat(func(f *gofakeit.Faker, level model.LabelValue, t time.Time) string {
+		return flog.NewCommonLogFormat(f, t, log.RandURI(f), statusFromLevel(level))
+	}),
+	"json": lineFormat(func(f *gofakeit.Faker, level model.LabelValue, t time.Time) string {
+		return flog.NewJSONLogFormat(f, t, log.RandURI(f), statusFromLevel(level))
+	}),
+	"json_mixed": func(svc ServiceConfig) LogGenerator {
+		levels := svc.LevelMix()
+		return func(ctx context.Context, stream *Stream) {
+			stream.Go(func(f *gofakeit.Faker, clock Clock) {
 				for ctx.Err() == nil {
-					level := log.RandLevel()
-					t := time.Now()
+					t := clock.Now()
+					level := stream.Incidents.level(f, t, levels)
+					entries := 0
 					if level == log.ERROR {
-						log := flog.NewCommonLogFormat(t, log.RandURI(), statusFromLevel(level))
+						log := flog.NewCommonLogFormat(f, t, log.RandURI(f), statusFromLevel(level))
 						// Add a stacktrace to the logfmt log, and include a field that will conflict with stream selectors
-						logger.LogWithMetadata(level, t, fmt.Sprintf("%s %s", log, `method=GET namespace=whoopsie caller=flush.go:253 stacktrace="Exception in thread \"main\" java.lang.NullPointerException\n        at com.example.myproject.Book.getTitle(Book.java:16)\n        at com.example.myproject.Author.getBookTitles(Author.java:25)\n        at com.example.myproject.Bootstrap.main(Bootstrap.java:14)"`), metadata)
+						entries += stream.log(f, level, t, fmt.Sprintf("%s %s", log, `method=GET namespace=whoopsie caller=flush.go:253 stacktrace="Exception in thread \"main\" java.lang.NullPointerException\n        at com.example.myproject.Book.getTitle(Book.java:16)\n        at com.example.myproject.Author.getBookTitles(Author.java:25)\n        at com.example.myproject.Bootstrap.main(Bootstrap.java:14)"`), stream.Metadata)
 					}
-					logger.LogWithMetadata(level, t, flog.NewJSONLogFormat(t, log.RandURI(), statusFromLevel(level)), metadata)
-					time.Sleep(time.Duration(rand.Intn(5000)) * time.Millisecond)
-				}
-			}()
-		},
-	},
-
-	"mimir-dev": {
-		"mimir-ingester":    mimirPod,
-		"mimir-distributor": mimirPod,
-		"mimir-querier":     mimirPod,
-		"mimir-ruler":       mimirPod,
-	},
-	"mimir-prod": {
-		"mimir-ingester": mimirPod,
-	},
-	"tempo-prod": {
-		"tempo-ingester":    noisyTempo,
-		"tempo-distributor": noisyTempo,
-	},
-	"tempo-dev": {
-		"tempo-ingester":    noisyTempo,
-		"tempo-distributor": noisyTempo,
-	},
-	"loki-otel": {
-		"loki-ingester-otel":      lokiOtelPod("loki-ingester-otel"),
-		"loki-querier-otel":       lokiOtelPod("loki-querier-otel"),
-		"loki-queryfrontend-otel": lokiOtelPod("loki-queryfrontend-otel"),
-		"loki-distributor-otel":   lokiOtelPod("loki-distributor-otel"),
-	},
-	"grafanacon": {
-		"grafanacon-json-otel": func(ctx context.Context, logger *log.AppLogger, metadata push.LabelsAdapter) {
-			go func() {
-				for ctx.Err() == nil {
-					level := log.RandLevel()
-					t := time.Now()
-					logger.LogWithMetadata(level, t, flog.NewJSONLogFormat(t, log.RandURI(), statusFromLevel(level)), metadata)
-					time.Sleep(time.Duration(rand.Intn(5000)) * time.Millisecond)
+					entries += stream.log(f, level, t, flog.NewJSONLogFormat(f, t, log.RandURI(f), statusFromLevel(level)), stream.Metadata)
+					clock.Sleep(ctx, stream.pause(f, t, entries))
 				}
-			}()
-		},
-		"grafanacon-otel": func(ctx context.Context, logger *log.AppLogger, metadata push.LabelsAdapter) {
-			go func() {
-				for ctx.Err() == nil {
-					level := log.RandLevel()
-					t := time.Now()
-					logger.LogWithMetadata(level, t, flog.NewJSONLogFormat(t, log.RandURI(), statusFromLevel(level)), metadata)
-					time.Sleep(time.Duration(rand.Intn(5000)) * time.Millisecond)
-				}
-			}()
-		},
+			})
+		}
 	},
-	"e-commerce": {
-		"shopping-cart-otel": func(ctx context.Context, logger *log.AppLogger, metadata push.LabelsAdapter) {
-			go func() {
-				for ctx.Err() == nil {
-					level := log.RandLevel()
-					t := time.Now()
-
-					var logLine string
-					if level == log.WARN {
-						logLine = fmt.Sprintf("order %d is not valid", gofakeit.Number(1, 10000))
-					} else if level == log.ERROR {
-						logLine = fmt.Sprintf("error processing order %d", gofakeit.Number(1, 10000))
-					} else {
-						logLine = flog.NewShoppingCart(t)
-					}
-					logger.LogWithMetadata(level, t, logLine, metadata)
-					time.Sleep(time.Duration(rand.Intn(5000)) * time.Millisecond)
-				}
-			}()
-		},
-		"shopping-cart-structured-otel": func(ctx context.Context, logger *log.AppLogger, metadata push.LabelsAdapter) {
-			go func() {
+	"shopping_cart": lineFormat(func(f *gofakeit.Faker, level model.LabelValue, t time.Time) string {
+		switch level {
+		case log.WARN:
+			return fmt.Sprintf("order %d is not valid", f.Number(1, 10000))
+		case log.ERROR:
+			return fmt.Sprintf("error processing order %d", f.Number(1, 10000))
+		default:
+			return flog.NewShoppingCart(f, t)
+		}
+	}),
+	"shopping_cart_structured": func(svc ServiceConfig) LogGenerator {
+		levels := svc.LevelMix()
+		return func(ctx context.Context, stream *Stream) {
+			metadata := stream.Metadata
+			stream.Go(func(f *gofakeit.Faker, clock Clock) {
 				for ctx.Err() == nil {
-					level := log.RandLevel()
-					t := time.Now()
+					t := clock.Now()
+					level := stream.Incidents.level(f, t, levels)
 
 					var logLine string
 					newLabels := metadata
 					if level == log.WARN {
-						logLine = fmt.Sprintf("order %d is not valid", gofakeit.Number(1, 10000))
+						logLine = fmt.Sprintf("order %d is not valid", f.Number(1, 10000))
 					} else if level == log.ERROR {
-						logLine = fmt.Sprintf("error processing order %d", gofakeit.Number(1, 10000))
+						logLine = fmt.Sprintf("error processing order %d", f.Number(1, 10000))
 					} else {
 						var labels push.LabelsAdapter
-						logLine, labels = flog.NewShoppingCartWithMetadata(t)
+						logLine, labels = flog.NewShoppingCartWithMetadata(f, t)
 						newLabels = make(push.LabelsAdapter, len(labels)+len(metadata))
 						for _, label := range labels {
 							newLabels = append(newLabels, label)
@@ -163,196 +147,181 @@ var generators = map[model.LabelValue]map[model.LabelValue]LogGenerator{
 						}
 					}
 
-					logger.LogWithMetadata(level, t, logLine, newLabels)
-					time.Sleep(time.Duration(rand.Intn(5000)) * time.Millisecond)
+					entries := stream.log(f, level, t, logLine, newLabels)
+					clock.Sleep(ctx, stream.pause(f, t, entries))
 				}
-			}()
-		},
+			})
+		}
+	},
+	"mimir": templateFormat(mimirTemplates),
+	"tempo": templateFormat(tempoTemplates),
+	// template writes the templates of the service, see LineTemplate.
+	"template": templateFormat(nil),
+	"loki_otel": func(svc ServiceConfig) LogGenerator {
+		return lokiOtelPod(svc)
 	},
+	"nginx_error": processFormat(func(f *gofakeit.Faker, level model.LabelValue, t time.Time, pid int) string {
+		// nginx workers are forked early by the master, their pids are small.
+		return flog.NewNginxErrorLog(f, t, string(level), pid%64+1)
+	}),
+	"postgres": processFormat(func(f *gofakeit.Faker, level model.LabelValue, t time.Time, pid int) string {
+		return flog.NewPostgresLog(f, t, string(level), pid)
+	}),
+	"mysql": lineFormat(func(f *gofakeit.Faker, level model.LabelValue, t time.Time) string {
+		return flog.NewMySQLErrorLog(f, t, string(level))
+	}),
+	"mysql_slow": lineFormat(func(f *gofakeit.Faker, _ model.LabelValue, t time.Time) string {
+		return flog.NewMySQLSlowLog(f, t)
+	}),
+	"redis": processFormat(func(f *gofakeit.Faker, level model.LabelValue, t time.Time, pid int) string {
+		return flog.NewRedisLog(f, t, string(level), pid)
+	}),
+	"kafka": processFormat(func(f *gofakeit.Faker, level model.LabelValue, t time.Time, pid int) string {
+		// The broker id is as stable as a pid.
+		return flog.NewKafkaLog(f, t, string(level), pid%3+1)
+	}),
+	// windows_event writes the Security, System and Application events of a host as loki.source.windowsevent ships them.
+	"windows_event":     windowsEventFormat(flog.WindowsEvent.JSON),
+	"windows_event_xml": windowsEventFormat(flog.WindowsEvent.XML),
 }
 
-func lokiOtelPod(svc string) LogGenerator {
-	logs := map[string]map[model.LabelValue]string{
-		"loki-ingester-otel": {
-			log.ERROR: lokiGRPCLog("connection refused to object store", "/loki.Ingester/Push"),
-			log.INFO:  lokiGRPCLog("", "/loki.Ingester/Push"),
-		},
-		"loki-querier-otel": {
-			log.INFO:  lokiGRPCLog("caller=engine.go:263 component=querier org_id=29 traceID=<_> msg=\"executing query\" query=<_> query_hash=1182293200 type=range length=20s step=4 token_id=123", "loki.Query/Engine"),
-			log.DEBUG: lokiGRPCLog("caller=scheduler_processor.go:135 component=querier msg=\"received query\" worker=<_> wait_time_sec=20s", "loki.Query/SchedulerProcessor"),
-		},
-		"loki-queryfrontend-otel": {
-			log.INFO: lokiGRPCLog("caller=roundtrip.go:419 org_id=29 traceID=213098 msg=\"executing query\" type=instant query=\"abc\" query_hash=120938", "loki.Query/QueryRange"),
-		},
-		"loki-distributor-otel": {
-			log.DEBUG: lokiGRPCLog("caller=push.go:165 org_id=29 traceID=192382 msg=\"push request parsed\" path=push.go contentType=application/x-protobuf contentEncoding= bodySize=129KB streams=12938 entries=81902398 streamLabelsSize=2KB entriesSize=2MB structuredMetadataSize=200KB totalSize=20MB mostRecentLagMs=10s", "loki.Distributor/Push"),
-			log.INFO:  lokiGRPCLog("caller=tee_service.go:273 msg=\"prepared Tee batches for tenant\" tenant=29 stream_count=100 avg_logs_slice_cap_start=120 avg_logs_slice_cap_end=123992 avg_logs_slice_len_end=10200 avg_log_lines_count=122300 avg_log_line_length=10s", "loki.Distributor/Tee"),
-		},
+func init() {
+	// Every runtime has a format named after it, writing the lines of its usual logger with a stack trace on errors.
+	for _, runtime := range flog.Runtimes {
+		formats[runtime] = lineFormat(func(f *gofakeit.Faker, level model.LabelValue, t time.Time) string {
+			return flog.NewRuntimeLog(f, t, runtime, string(level))
+		})
 	}
-	return func(ctx context.Context, logger *log.AppLogger, metadata push.LabelsAdapter) {
-		serviceLogs := logs[svc]
-		for k, v := range serviceLogs {
-			go func() {
+}
+
+// lineFormat builds a LogGenerator writing lines at the stream's rate, with the level drawn from the service's level mix.
+func lineFormat(line func(f *gofakeit.Faker, level model.LabelValue, t time.Time) string) func(svc ServiceConfig) LogGenerator {
+	return processFormat(func(f *gofakeit.Faker, level model.LabelValue, t time.Time, _ int) string {
+		return line(f, level, t)
+	})
+}
+
+// processFormat is lineFormat for the logs of a server process, all the lines of a stream share its pid.
+func processFormat(line func(f *gofakeit.Faker, level model.LabelValue, t time.Time, pid int) string) func(svc ServiceConfig) LogGenerator {
+	return func(svc ServiceConfig) LogGenerator {
+		levels := svc.LevelMix()
+		return func(ctx context.Context, stream *Stream) {
+			stream.Go(func(f *gofakeit.Faker, clock Clock) {
+				pid := f.Number(1, 32768)
 				for ctx.Err() == nil {
-					t := time.Now()
-					logger.LogWithMetadata(k, t, v, log.RandStructuredMetadata("loki-ingester", 0))
-					time.Sleep(time.Duration(rand.Intn(5000)) * time.Millisecond)
+					t := clock.Now()
+					level := stream.Incidents.level(f, t, levels)
+					entries := stream.log(f, level, t, line(f, level, t, pid), stream.Metadata)
+					clock.Sleep(ctx, stream.pause(f, t, entries))
 				}
-			}()
+			})
 		}
 	}
 }
 
-var noisyTempo = func(ctx context.Context, logger *log.AppLogger, metadata push.LabelsAdapter) {
-	const fmt1 = `level=debug ts=%s caller=broadcast.go:48 msg="Invalidating forwarded broadcast" key=collectors/compactor version=%d oldVersion=%d content=[compactor-%s] oldContent=[compactor-%s]`
-	const fmt2 = `level=warn ts=%s caller=instance.go:43 msg="TRACE_TOO_LARGE: max size of trace (52428800) exceeded tenant %s"`
-	const fmt3 = `level=info ts=%s caller=compactor.go:242 msg="flushed to block" bytes=%dB objects=%d values=%d`
-	const fmt4 = `level=info ts=%s caller=poller.go:133 msg="blocklist poll complete" seconds=%d`
-	const fmt5 = `level=info ts=%s caller=flush.go:253 msg="completing block" userid=%s blockID=%s`
-	const fmt6 = `level=error ts=%s caller=memcached.go:153 msg="Failed to get keys from memcached" err="memcache: connect timeout to %s:11211"`
-	const fmt7 = `level=info ts=%s caller=registry.go:232 tenant=%s msg="collecting metrics" active_series=%d`
-	const fmt8 = `level=info ts=%s caller=main.go:107 msg="Starting Grafana Enterprise Traces" version="version=weekly-r138-f1920489, branch=weekly-r138, revision=f1920489"`
-	go func() {
-		for ctx.Err() == nil {
-			t := time.Now()
-			logger.LogWithMetadata(log.DEBUG, t, fmt.Sprintf(fmt1, t.Format(time.RFC3339Nano), rand.Intn(100), rand.Intn(100), log.RandSeq(5), log.RandSeq(5)), metadata)
-			time.Sleep(time.Duration(rand.Intn(1000)) * time.Millisecond)
-		}
-	}()
-	go func() {
-		for ctx.Err() == nil {
-			t := time.Now()
-			logger.LogWithMetadata(log.WARN, t, fmt.Sprintf(fmt2, t.Format(time.RFC3339Nano), log.RandOrgID()), metadata)
-			time.Sleep(time.Duration(rand.Intn(3000)) * time.Millisecond)
-		}
-	}()
-	go func() {
-		for ctx.Err() == nil {
-			t := time.Now()
-			logger.LogWithMetadata(log.INFO, t, fmt.Sprintf(fmt3, t.Format(time.RFC3339Nano), rand.Intn(1000), rand.Intn(1000), rand.Intn(1000)), metadata)
-			time.Sleep(time.Duration(rand.Intn(4000)) * time.Millisecond)
-		}
-	}()
-	go func() {
-		for ctx.Err() == nil {
-			t := time.Now()
-			logger.LogWithMetadata(log.INFO, t, fmt.Sprintf(fmt4, t.Format(time.RFC3339Nano), rand.Intn(1000)), metadata)
-			time.Sleep(time.Duration(rand.Intn(7000)) * time.Millisecond)
-		}
-	}()
-	go func() {
-		for ctx.Err() == nil {
-			t := time.Now()
-			logger.LogWithMetadata(log.INFO, t, fmt.Sprintf(fmt5, t.Format(time.RFC3339Nano), log.RandOrgID(), log.RandSeq(5)), metadata)
-			time.Sleep(time.Duration(rand.Intn(1000)) * time.Millisecond)
-		}
-	}()
-	go func() {
-		for ctx.Err() == nil {
-			t := time.Now()
-			logger.LogWithMetadata(log.ERROR, t, fmt.Sprintf(fmt6, t.Format(time.RFC3339Nano), flog.FakeIP()), metadata)
-			time.Sleep(time.Duration(rand.Intn(2000)) * time.Millisecond)
-		}
-	}()
-	go func() {
-		for ctx.Err() == nil {
-			t := time.Now()
-			logger.LogWithMetadata(log.INFO, t, fmt.Sprintf(fmt7, t.Format(time.RFC3339Nano), log.RandOrgID(), rand.Intn(1000)), metadata)
-			time.Sleep(time.Duration(rand.Intn(5000)) * time.Millisecond)
-		}
-	}()
-	go func() {
-		for ctx.Err() == nil {
-			t := time.Now()
-			logger.LogWithMetadata(log.INFO, t, fmt.Sprintf(fmt8, t.Format(time.RFC3339Nano)), metadata)
-			time.Sleep(20 * time.Second)
+// windowsEventFormat is lineFormat for the events of a Windows host, each stream is a computer numbering its events.
+func windowsEventFormat(render func(flog.WindowsEvent) string) func(svc ServiceConfig) LogGenerator {
+	return func(svc ServiceConfig) LogGenerator {
+		levels := svc.LevelMix()
+		return func(ctx context.Context, stream *Stream) {
+			stream.Go(func(f *gofakeit.Faker, clock Clock) {
+				computer := fmt.Sprintf("WIN-%X.corp.example.com", f.Number(0x10000000, 0x7fffffff))
+				recordID := f.Number(1, 1000000)
+				for ctx.Err() == nil {
+					t := clock.Now()
+					level := stream.Incidents.level(f, t, levels)
+					recordID++
+					entries := stream.log(f, level, t, render(flog.NewWindowsEvent(f, t, string(level), computer, recordID)), stream.Metadata)
+					clock.Sleep(ctx, stream.pause(f, t, entries))
+				}
+			})
 		}
-	}()
+	}
 }
 
-var mimirPod = func(ctx context.Context, logger *log.AppLogger, metadata push.LabelsAdapter) {
-	go func() {
-		for ctx.Err() == nil {
-			t := time.Now()
-			logger.LogWithMetadata(log.INFO, t, mimirGRPCLog("", "/cortex.Ingester/Push"), metadata)
-			time.Sleep(time.Duration(rand.Intn(5000)) * time.Millisecond)
-		}
-	}()
-}
+// grpcLog is the gRPC request line of Mimir and Loki components, with the level, method and err filled in.
+const grpcLog = `ts={{ts}} caller=grpc_logging.go:66 tenant={{orgID}} level=info method=%s duration={{duration}} msg=gRPC`
 
-func startFailingMimirPod(ctx context.Context, logger log.Logger) {
-	appLogger := log.NewAppLogger(model.LabelSet{
-		"cluster":      model.LabelValue(log.Clusters[0]),
-		"namespace":    model.LabelValue("mimir"),
-		"service_name": "mimir-ingester",
-	}, logger)
+// grpcErrorLog is grpcLog failing with err, for one of the first tenants only.
+const grpcErrorLog = `ts={{ts}} caller=grpc_logging.go:66 tenant={{choice 1218 29 1010}} level=error method=%s duration={{duration}} msg=gRPC err="%s"`
 
-	go func() {
-		for ctx.Err() == nil {
-			t := time.Now()
-			appLogger.LogWithMetadata(log.ERROR, t, mimirGRPCLog("connection refused to object store", "/cortex.Ingester/Push"), log.RandStructuredMetadata("mimir-ingester", 0))
-			time.Sleep(time.Duration(rand.Intn(10000)) * time.Millisecond)
-		}
-	}()
-	go func() {
-		for ctx.Err() == nil {
-			t := time.Now()
-			appLogger.LogWithMetadata(log.INFO, t, mimirGRPCLog("", "/cortex.Ingester/Push"), log.RandStructuredMetadata("mimir-ingester", 0))
-			time.Sleep(time.Duration(rand.Intn(500)) * time.Millisecond)
-		}
-	}()
+var mimirTemplates = []LineTemplate{
+	{Line: fmt.Sprintf(grpcLog, "/cortex.Ingester/Push"), Level: "info", Weight: 95},
+	{Line: fmt.Sprintf(grpcErrorLog, "/cortex.Ingester/Push", "connection refused to object store"), Level: "error", Weight: 5},
 }
 
-const (
-	mimirGrpcLogFmt = `ts=%s caller=grpc_logging.go:66 tenant=%s level=%s method=%s duration=%s msg=gRPC`
-	lokiGrpcLogFmt  = `ts=%s caller=grpc_logging.go:66 tenant=%s level=%s method=%s duration=%s msg=gRPC`
-	// we need another app may be pyrscope and many different pattern this time to make pattern tab interesting.
-)
+// tempoTemplates are weighted by the relative frequency of the lines in a real ingester.
+var tempoTemplates = []LineTemplate{
+	{Line: `level=debug ts={{ts}} caller=broadcast.go:48 msg="Invalidating forwarded broadcast" key=collectors/compactor version={{int 100}} oldVersion={{int 100}} content=[compactor-{{seq 5}}] oldContent=[compactor-{{seq 5}}]`, Level: "debug", Weight: 40},
+	{Line: `level=warn ts={{ts}} caller=instance.go:43 msg="TRACE_TOO_LARGE: max size of trace (52428800) exceeded tenant {{orgID}}"`, Level: "warn", Weight: 13},
+	{Line: `level=info ts={{ts}} caller=compactor.go:242 msg="flushed to block" bytes={{int 1000}}B objects={{int 1000}} values={{int 1000}}`, Level: "info", Weight: 10},
+	{Line: `level=info ts={{ts}} caller=poller.go:133 msg="blocklist poll complete" seconds={{int 1000}}`, Level: "info", Weight: 6},
+	{Line: `level=info ts={{ts}} caller=flush.go:253 msg="completing block" userid={{orgID}} blockID={{seq 5}}`, Level: "info", Weight: 40},
+	{Line: `level=error ts={{ts}} caller=memcached.go:153 msg="Failed to get keys from memcached" err="memcache: connect timeout to {{ip}}:11211"`, Level: "error", Weight: 20},
+	{Line: `level=info ts={{ts}} caller=registry.go:232 tenant={{orgID}} msg="collecting metrics" active_series={{int 1000}}`, Level: "info", Weight: 8},
+	{Line: `level=info ts={{ts}} caller=main.go:107 msg="Starting Grafana Enterprise Traces" version="version=weekly-r138-f1920489, branch=weekly-r138, revision=f1920489"`, Level: "info", Weight: 1},
+}
 
-func mimirGRPCLog(err string, path string) string {
-	level := log.INFO
-	org := log.RandOrgID()
-	if err != "" {
-		level = log.ERROR
-		org = log.OrgIDs[rand.Intn(len(log.OrgIDs[2:]))]
-	}
+// lokiOtelTemplates are the lines of each loki_otel service.
+var lokiOtelTemplates = map[string][]LineTemplate{
+	"loki-ingester-otel": {
+		{Line: fmt.Sprintf(grpcLog, "/loki.Ingester/Push"), Level: "info"},
+		{Line: fmt.Sprintf(grpcErrorLog, "/loki.Ingester/Push", "connection refused to object store"), Level: "error"},
+	},
+	"loki-querier-otel": {
+		{Line: fmt.Sprintf(grpcErrorLog, "loki.Query/SchedulerProcessor", `caller=scheduler_processor.go:135 component=querier msg="received query" worker=<_> wait_time_sec=20s`), Level: "debug"},
+		{Line: fmt.Sprintf(grpcErrorLog, "loki.Query/Engine", `caller=engine.go:263 component=querier org_id=29 traceID=<_> msg="executing query" query=<_> query_hash=1182293200 type=range length=20s step=4 token_id=123`), Level: "info"},
+	},
+	"loki-queryfrontend-otel": {
+		{Line: fmt.Sprintf(grpcErrorLog, "loki.Query/QueryRange", `caller=roundtrip.go:419 org_id=29 traceID=213098 msg="executing query" type=instant query="abc" query_hash=120938`), Level: "info"},
+	},
+	"loki-distributor-otel": {
+		{Line: fmt.Sprintf(grpcErrorLog, "loki.Distributor/Push", `caller=push.go:165 org_id=29 traceID=192382 msg="push request parsed" path=push.go contentType=application/x-protobuf contentEncoding= bodySize=129KB streams=12938 entries=81902398 streamLabelsSize=2KB entriesSize=2MB structuredMetadataSize=200KB totalSize=20MB mostRecentLagMs=10s`), Level: "debug"},
+		{Line: fmt.Sprintf(grpcErrorLog, "loki.Distributor/Tee", `caller=tee_service.go:273 msg="prepared Tee batches for tenant" tenant=29 stream_count=100 avg_logs_slice_cap_start=120 avg_logs_slice_cap_end=123992 avg_logs_slice_len_end=10200 avg_log_lines_count=122300 avg_log_line_length=10s`), Level: "info"},
+	},
+}
 
-	log := fmt.Sprintf(
-		mimirGrpcLogFmt,
-		time.Now().Format(time.RFC3339Nano),
-		org,
-		level,
-		path,
-		log.RandDuration(),
-	)
-	if err != "" {
-		log += ` err="` + err + `"`
+// lokiOtelPod writes the lokiOtelTemplates of the service, each line with its own structured metadata.
+func lokiOtelPod(svc ServiceConfig) LogGenerator {
+	lines := mustCompileTemplates(lokiOtelTemplates[svc.Name])
+	return func(ctx context.Context, stream *Stream) {
+		if len(lines) == 0 {
+			return
+		}
+		stream.Go(func(f *gofakeit.Faker, clock Clock) {
+			traceID := ""
+			for ctx.Err() == nil {
+				t := clock.Now()
+				metadata := log.RandStructuredMetadata(f, "loki-ingester", 0, traceID)
+				traceID = metadata[0].Value
+				l := stream.Incidents.pickLine(f, t, lines)
+				entries := stream.log(f, l.level, t, l.line(f, t), metadata)
+				clock.Sleep(ctx, stream.pause(f, t, entries))
+			}
+		})
 	}
+}
 
-	return log
+// weightedLine is a line picked with a probability proportional to its weight.
+type weightedLine struct {
+	weight int
+	level  model.LabelValue
+	line   func(f *gofakeit.Faker, t time.Time) string
 }
 
-func lokiGRPCLog(err, path string) string {
-	level := log.INFO
-	org := log.RandOrgID()
-	if err != "" {
-		level = log.ERROR
-		org = log.OrgIDs[rand.Intn(len(log.OrgIDs[2:]))]
+func pickLine(f *gofakeit.Faker, lines []weightedLine) weightedLine {
+	total := 0
+	for _, l := range lines {
+		total += l.weight
 	}
-
-	log := fmt.Sprintf(
-		lokiGrpcLogFmt,
-		time.Now().Format(time.RFC3339Nano),
-		org,
-		level,
-		path,
-		log.RandDuration(),
-	)
-	if err != "" {
-		log += ` err="` + err + `"`
+	n := f.IntN(total)
+	for _, l := range lines {
+		if n < l.weight {
+			return l
+		}
+		n -= l.weight
 	}
-
-	return log
+	return lines[len(lines)-1]
 }
 
 func statusFromLevel(level model.LabelValue) int {
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/grafana/explore-logs/generator/log"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClock advances instantly, and cancels the generation once end is reached.
+type fakeClock struct {
+	now, end time.Time
+	cancel   context.CancelFunc
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Sleep(_ context.Context, d time.Duration) {
+	c.now = c.now.Add(d)
+	if !c.now.Before(c.end) {
+		c.cancel()
+	}
+}
+
+func TestStreamPause(t *testing.T) {
+	stream := &Stream{Rate: 4}
+	f := gofakeit.New(1)
+	for _, entries := range []int{0, 1, 3} {
+		var total time.Duration
+		for range 10000 {
+			total += stream.pause(f, time.Now(), entries)
+		}
+		// Every entry, or dropped line, is a quarter of a second.
+		assert.InEpsilon(t, float64(max(entries, 1))*0.25, (total / 10000).Seconds(), 0.03, "%d entries", entries)
+	}
+}
+
+func TestStreamRate(t *testing.T) {
+	for _, tc := range []struct {
+		format     string
+		splitLines bool
+	}{
+		{format: "json"},
+		{format: "json_mixed"},
+		{format: "java", splitLines: true},
+	} {
+		t.Run(tc.format, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			start := time.Date(2024, 10, 1, 14, 0, 0, 0, time.UTC)
+			clock := &fakeClock{now: start, end: start.Add(time.Hour), cancel: cancel}
+			var sink recorder
+			var running sync.WaitGroup
+			stream := &Stream{
+				Logger:     log.NewAppLogger(model.LabelSet{"service_name": "test"}, sink.logger()),
+				Rand:       gofakeit.New(1),
+				Clocks:     func() (Clock, func()) { return clock, func() {} },
+				Rate:       5,
+				Running:    &running,
+				SplitLines: tc.splitLines,
+			}
+			svc := ServiceConfig{Format: tc.format, Levels: map[string]int{"info": 1, "error": 1}}
+			formats[tc.format](svc)(ctx, stream)
+			running.Wait()
+
+			assert.InEpsilon(t, 5, float64(sink.len())/time.Hour.Seconds(), 0.05)
+		})
+	}
+}
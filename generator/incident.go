@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/grafana/explore-logs/generator/log"
+	"github.com/prometheus/common/model"
+)
+
+// Incident types, see IncidentConfig.Intensity for what intensity means to each of them.
+const (
+	errorSpike = "error_spike"
+	latency    = "latency"
+	newPattern = "new_pattern"
+	silence    = "silence"
+)
+
+// defaultIntensities are used for incidents without an intensity.
+var defaultIntensities = map[string]float64{
+	errorSpike: 0.5,
+	latency:    10,
+	newPattern: 0.2,
+	silence:    1,
+}
+
+// IncidentConfig describes a disruption applied on top of the matching streams of a scenario.
+type IncidentConfig struct {
+	Name string `yaml:"name"`
+	// Type is one of error_spike, latency, new_pattern or silence.
+	Type string `yaml:"type"`
+	// Namespace, Service and Cluster select the affected streams, an empty selector matches all of them.
+	Namespace string `yaml:"namespace"`
+	Service   string `yaml:"service"`
+	Cluster   string `yaml:"cluster"`
+	// Start is an RFC3339 time, or an offset from the start of the generation such as 10m. The incident starts with
+	// the generation when unset.
+	Start string `yaml:"start"`
+	// Duration is how long the incident lasts, it never ends when unset.
+	Duration time.Duration `yaml:"duration"`
+	// Intensity is the share of lines turned into errors for error_spike, or into Message for new_pattern.
+	// For latency, it is the factor applied to the durations found in the lines and to the pauses between lines.
+	Intensity float64 `yaml:"intensity"`
+	// Message is the line of a new_pattern incident, written at Level (error when unset).
+	Message string `yaml:"message"`
+	Level   string `yaml:"level"`
+}
+
+func (c IncidentConfig) validate() error {
+	if _, ok := defaultIntensities[c.Type]; !ok {
+		return fmt.Errorf("unknown type %q", c.Type)
+	}
+	if c.Duration < 0 {
+		return fmt.Errorf("duration can not be negative")
+	}
+	switch {
+	case c.Intensity < 0:
+		return fmt.Errorf("intensity can not be negative")
+	case (c.Type == errorSpike || c.Type == newPattern) && c.Intensity > 1:
+		return fmt.Errorf("%s intensity is a share of lines, between 0 and 1", c.Type)
+	}
+	if c.Type == newPattern && c.Message == "" {
+		return fmt.Errorf("new_pattern without a message")
+	}
+	if c.Level != "" && !slices.Contains(log.Levels, model.LabelValue(c.Level)) {
+		return fmt.Errorf("unknown level %q", c.Level)
+	}
+	if _, err := parseOffset(c.Start, time.Time{}); err != nil {
+		return err
+	}
+	return nil
+}
+
+// parseOffset parses an RFC3339 time, or a duration after start.
+func parseOffset(s string, start time.Time) (time.Time, error) {
+	if s == "" {
+		return start, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	d, err := model.ParseDuration(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("start %q is neither an RFC3339 time nor an offset", s)
+	}
+	return start.Add(time.Duration(d)), nil
+}
+
+type incident struct {
+	IncidentConfig
+	from, to  time.Time
+	intensity float64
+}
+
+// Incidents are the incidents of a scenario, scheduled from the start of the generation.
+type Incidents []*incident
+
+// NewIncidents schedules the incidents of configs from start.
+func NewIncidents(configs []IncidentConfig, start time.Time) (Incidents, error) {
+	incidents := make(Incidents, 0, len(configs))
+	for _, c := range configs {
+		from, err := parseOffset(c.Start, start)
+		if err != nil {
+			return nil, fmt.Errorf("incident %s: %w", c.Name, err)
+		}
+		i := &incident{IncidentConfig: c, from: from, intensity: c.Intensity}
+		if c.Duration > 0 {
+			i.to = from.Add(c.Duration)
+		}
+		if i.intensity == 0 {
+			i.intensity = defaultIntensities[c.Type]
+		}
+		if i.Level == "" {
+			i.Level = string(log.ERROR)
+		}
+		incidents = append(incidents, i)
+	}
+	return incidents, nil
+}
+
+// For returns the incidents affecting the stream with labels.
+func (is Incidents) For(labels model.LabelSet) Incidents {
+	var matching Incidents
+	for _, i := range is {
+		if matches(i.Namespace, labels["namespace"]) && matches(i.Service, labels["service_name"]) && matches(i.Cluster, labels["cluster"]) {
+			matching = append(matching, i)
+		}
+	}
+	return matching
+}
+
+func matches(selector string, value model.LabelValue) bool {
+	return selector == "" || selector == string(value)
+}
+
+// active returns the first incident of type typ ongoing at t.
+func (is Incidents) active(typ string, t time.Time) *incident {
+	for _, i := range is {
+		if i.Type == typ && !t.Before(i.from) && (i.to.IsZero() || t.Before(i.to)) {
+			return i
+		}
+	}
+	return nil
+}
+
+// level draws the level of the next line from levels, turned into an error during an error spike.
+func (is Incidents) level(f *gofakeit.Faker, t time.Time, levels log.LevelMix) model.LabelValue {
+	if i := is.active(errorSpike, t); i != nil && f.Float64() < i.intensity {
+		return log.ERROR
+	}
+	return levels.Rand(f)
+}
+
+// pickLine picks the next line of lines, an error line during an error spike if there is one.
+func (is Incidents) pickLine(f *gofakeit.Faker, t time.Time, lines []weightedLine) weightedLine {
+	if i := is.active(errorSpike, t); i != nil && f.Float64() < i.intensity {
+		var errorLines []weightedLine
+		for _, l := range lines {
+			if l.level == log.ERROR {
+				errorLines = append(errorLines, l)
+			}
+		}
+		if len(errorLines) > 0 {
+			return pickLine(f, errorLines)
+		}
+	}
+	return pickLine(f, lines)
+}
+
+// apply rewrites a line according to the ongoing incidents, ok is false when the line must be dropped.
+func (is Incidents) apply(f *gofakeit.Faker, t time.Time, level model.LabelValue, line string) (model.LabelValue, string, bool) {
+	if is.active(silence, t) != nil {
+		return level, line, false
+	}
+	if i := is.active(newPattern, t); i != nil && f.Float64() < i.intensity {
+		return model.LabelValue(i.Level), i.Message, true
+	}
+	if i := is.active(latency, t); i != nil {
+		line = scaleDurations(line, i.intensity)
+	}
+	return level, line, true
+}
+
+// pause stretches the pause before the next line during a latency degradation.
+func (is Incidents) pause(t time.Time, d time.Duration) time.Duration {
+	if i := is.active(latency, t); i != nil {
+		return time.Duration(float64(d) * i.intensity)
+	}
+	return d
+}
+
+var durationField = regexp.MustCompile(`(duration=)((?:[0-9.]+(?:ns|us|µs|ms|s|m|h))+)`)
+
+// scaleDurations multiplies the duration= fields of line by factor.
+func scaleDurations(line string, factor float64) string {
+	return durationField.ReplaceAllStringFunc(line, func(field string) string {
+		m := durationField.FindStringSubmatch(field)
+		d, err := time.ParseDuration(m[2])
+		if err != nil {
+			return field
+		}
+		return m[1] + time.Duration(float64(d)*factor).Round(time.Millisecond).String()
+	})
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/grafana/explore-logs/generator/log"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIncidentsSchedule(t *testing.T) {
+	start := time.Date(2024, 10, 1, 14, 0, 0, 0, time.UTC)
+	incidents, err := NewIncidents([]IncidentConfig{
+		{Name: "spike", Type: errorSpike, Namespace: "gateway", Cluster: "eu-west-1", Start: "5m", Duration: 10 * time.Minute, Intensity: 1},
+		{Name: "outage", Type: silence, Service: "httpd", Start: "2024-10-01T14:30:00Z"},
+	}, start)
+	require.NoError(t, err)
+
+	nginx := incidents.For(model.LabelSet{"namespace": "gateway", "service_name": "nginx", "cluster": "eu-west-1"})
+	require.Len(t, nginx, 1)
+	assert.Nil(t, nginx.active(errorSpike, start.Add(4*time.Minute)))
+	assert.NotNil(t, nginx.active(errorSpike, start.Add(5*time.Minute)))
+	assert.Nil(t, nginx.active(errorSpike, start.Add(15*time.Minute)))
+
+	f := gofakeit.New(1)
+	assert.Equal(t, log.ERROR, nginx.level(f, start.Add(10*time.Minute), log.LevelMix{{Level: log.INFO, Weight: 1}}))
+	assert.Empty(t, incidents.For(model.LabelSet{"namespace": "gateway", "service_name": "nginx", "cluster": "us-east-1"}))
+
+	httpd := incidents.For(model.LabelSet{"namespace": "gateway", "service_name": "httpd", "cluster": "us-east-1"})
+	_, _, ok := httpd.apply(f, start.Add(29*time.Minute), log.INFO, "line")
+	assert.True(t, ok)
+	_, _, ok = httpd.apply(f, start.Add(24*time.Hour), log.INFO, "line")
+	assert.False(t, ok, "an incident without duration never ends")
+}
+
+func TestScaleDurations(t *testing.T) {
+	assert.Equal(t, "method=/cortex.Ingester/Push duration=2m16.08s msg=gRPC", scaleDurations("method=/cortex.Ingester/Push duration=13.608s msg=gRPC", 10))
+	assert.Equal(t, "no durations here", scaleDurations("no durations here", 10))
+}
+
+func TestLoadIncidents(t *testing.T) {
+	scenario, err := LoadScenario("")
+	require.NoError(t, err)
+	require.NoError(t, scenario.LoadIncidents("scenarios/incidents-example.yaml"))
+
+	scenario.Incidents = append(scenario.Incidents, IncidentConfig{Name: "typo", Type: silence, Service: "ngnix"})
+	assert.Error(t, scenario.Validate())
+}
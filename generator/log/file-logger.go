@@ -0,0 +1,124 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/explore-logs/generator/flog"
+	"github.com/grafana/loki/pkg/push"
+	"github.com/prometheus/common/model"
+)
+
+// FileLogger implements the Logger interface and writes the lines of every stream to its own rotating file,
+// as <dir>/<namespace>/<service_name>/<other label values>.log.
+type FileLogger struct {
+	dir  string
+	opts flog.RotateOptions
+
+	mu    sync.Mutex
+	files map[string]*streamFile
+}
+
+type streamFile struct {
+	mu     sync.Mutex
+	writer *flog.RotatingWriter
+}
+
+// NewFileLogger creates a logger writing under dir.
+func NewFileLogger(dir string, opts flog.RotateOptions) *FileLogger {
+	return &FileLogger{
+		dir:   dir,
+		opts:  opts,
+		files: map[string]*streamFile{},
+	}
+}
+
+// Handle implements the Logger interface
+func (l *FileLogger) Handle(labels model.LabelSet, timestamp time.Time, message string) error {
+	return l.HandleWithMetadata(labels, timestamp, message, nil)
+}
+
+// HandleWithMetadata implements the Logger interface. Files only hold the lines, like the ones written by an
+// application, the timestamp and metadata are dropped.
+func (l *FileLogger) HandleWithMetadata(labels model.LabelSet, _ time.Time, message string, _ push.LabelsAdapter) error {
+	file, err := l.file(l.path(labels))
+	if err != nil {
+		return err
+	}
+	file.mu.Lock()
+	defer file.mu.Unlock()
+	_, err = file.writer.Write([]byte(message + "\n"))
+	return err
+}
+
+func (l *FileLogger) file(path string) (*streamFile, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if file, ok := l.files[path]; ok {
+		return file, nil
+	}
+	writer, err := flog.NewRotatingWriter(path, l.opts)
+	if err != nil {
+		return nil, err
+	}
+	file := &streamFile{writer: writer}
+	l.files[path] = file
+	return file, nil
+}
+
+// path returns the file of the stream. The level is left out, so that a file holds all the lines of a stream.
+func (l *FileLogger) path(labels model.LabelSet) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		switch name {
+		case "namespace", "service_name", "level", "file":
+			continue
+		}
+		if strings.HasPrefix(string(name), "__") {
+			continue
+		}
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+
+	values := make([]string, 0, len(names))
+	for _, name := range names {
+		values = append(values, sanitizeFileName(string(labels[model.LabelName(name)])))
+	}
+	name := strings.Join(values, "_")
+	if name == "" {
+		name = "stream"
+	}
+	return filepath.Join(l.dir, sanitizeFileName(string(labels["namespace"])), sanitizeFileName(string(labels["service_name"])), name+".log")
+}
+
+func sanitizeFileName(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ':' || r == 0 {
+			return '_'
+		}
+		return r
+	}, s)
+}
+
+// Close implements the Logger interface, closing every file.
+func (l *FileLogger) Close(context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var errs []error
+	for path, file := range l.files {
+		file.mu.Lock()
+		errs = append(errs, file.writer.Close())
+		file.mu.Unlock()
+		delete(l.files, path)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,33 @@
+package log
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/grafana/explore-logs/generator/flog"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileLogger(t *testing.T) {
+	dir := t.TempDir()
+	logger := NewFileLogger(dir, flog.RotateOptions{})
+
+	labels := model.LabelSet{"namespace": "gateway", "service_name": "nginx", "cluster": "us-west-1", "env": "prod"}
+	require.NoError(t, logger.Handle(labels.Merge(model.LabelSet{"level": "info"}), time.Now(), "first"))
+	require.NoError(t, logger.Handle(labels.Merge(model.LabelSet{"level": "error"}), time.Now(), "second"))
+	require.NoError(t, logger.Handle(model.LabelSet{"service_name": "a/b"}, time.Now(), "third"))
+	require.NoError(t, logger.Close(context.Background()))
+
+	data, err := os.ReadFile(filepath.Join(dir, "gateway", "nginx", "us-west-1_prod.log"))
+	require.NoError(t, err)
+	assert.Equal(t, "first\nsecond\n", string(data), "the level does not split streams")
+
+	data, err = os.ReadFile(filepath.Join(dir, "unknown", "a_b", "stream.log"))
+	require.NoError(t, err)
+	assert.Equal(t, "third\n", string(data))
+}
@@ -1,6 +1,7 @@
 package log
 
 import (
+	"context"
 	"time"
 
 	"github.com/grafana/loki/pkg/push"
@@ -10,6 +11,13 @@ import (
 type Logger interface {
 	Handle(labels model.LabelSet, timestamp time.Time, message string) error
 	HandleWithMetadata(labels model.LabelSet, timestamp time.Time, message string, metadata push.LabelsAdapter) error
+	Closer
+}
+
+// Closer is a sink, or the resources shared by several ones.
+type Closer interface {
+	// Close flushes the pending lines and releases the sink, giving up once ctx is done.
+	Close(ctx context.Context) error
 }
 
 type LoggerFunc func(labels model.LabelSet, timestamp time.Time, message string, metadata push.LabelsAdapter) error
@@ -20,3 +28,8 @@ func (f LoggerFunc) Handle(labels model.LabelSet, timestamp time.Time, message s
 func (f LoggerFunc) HandleWithMetadata(labels model.LabelSet, timestamp time.Time, message string, metadata push.LabelsAdapter) error {
 	return f(labels, timestamp, message, metadata)
 }
+
+// Close implements the Logger interface, there is nothing to flush.
+func (f LoggerFunc) Close(context.Context) error {
+	return nil
+}
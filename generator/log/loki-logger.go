@@ -0,0 +1,485 @@
+package log
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/grafana/loki/pkg/push"
+	"github.com/prometheus/common/model"
+)
+
+// Encodings of the Loki push payload.
+const (
+	// ProtobufEncoding is the snappy compressed protobuf payload sent by most agents.
+	ProtobufEncoding = "protobuf"
+	// JSONEncoding is the JSON payload, optionally gzip compressed.
+	JSONEncoding = "json"
+)
+
+// ErrLoggerClosed is returned when handling a line after Close.
+var ErrLoggerClosed = errors.New("logger closed")
+
+// LokiConfig configures a LokiLogger.
+type LokiConfig struct {
+	// URL is the push endpoint, such as http://localhost:3100/loki/api/v1/push.
+	URL      string
+	TenantID string
+	// Username and Password are sent as basic auth when Password is set.
+	Username string
+	Password string
+
+	// Encoding is ProtobufEncoding or JSONEncoding, Gzip compresses JSON payloads.
+	Encoding string
+	Gzip     bool
+
+	// BatchSize is the size in bytes at which a batch is pushed, BatchWait the longest a line waits for its batch.
+	BatchSize int
+	BatchWait time.Duration
+
+	// A batch failing with a 429, a 5xx or a network error is retried up to MaxRetries times, waiting for the
+	// Retry-After of 429 responses or an exponential backoff between MinBackoff and MaxBackoff with jitter.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	MaxRetries int
+	// Timeout of a single push request.
+	Timeout time.Duration
+
+	// OnStreamError is called for every stream of a batch that was not ingested, with the number of lost lines.
+	OnStreamError func(labels string, entries int, err error)
+	// OnPush is called with the time every push request took.
+	OnPush func(d time.Duration)
+}
+
+// DefaultLokiConfig returns the configuration of a LokiLogger pushing to url.
+func DefaultLokiConfig(url string) LokiConfig {
+	return LokiConfig{
+		URL:        url,
+		Encoding:   ProtobufEncoding,
+		BatchSize:  1 << 20,
+		BatchWait:  time.Second,
+		MinBackoff: 100 * time.Millisecond,
+		MaxBackoff: 10 * time.Second,
+		MaxRetries: 5,
+		Timeout:    10 * time.Second,
+	}
+}
+
+// PushError is a failed push request.
+type PushError struct {
+	// StatusCode is the status of Loki's response, zero when the request did not get one.
+	StatusCode int
+	Message    string
+	// RetryAfter is the delay asked by a 429 response.
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *PushError) Error() string {
+	if e.StatusCode == 0 {
+		return fmt.Sprintf("push failed: %s", e.Err)
+	}
+	return fmt.Sprintf("push failed with status %d: %s", e.StatusCode, e.Message)
+}
+
+func (e *PushError) Unwrap() error {
+	return e.Err
+}
+
+// Reason classifies the error for the push errors metric.
+func (e *PushError) Reason() string {
+	switch {
+	case e.StatusCode == 0:
+		return "network"
+	case e.StatusCode == http.StatusTooManyRequests:
+		return "rate_limited"
+	case e.StatusCode >= 500:
+		return "server_error"
+	default:
+		return "rejected"
+	}
+}
+
+func (e *PushError) retryable() bool {
+	return e.StatusCode == 0 || e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// LokiLogger implements the Logger interface and pushes batches of lines to Loki's push API.
+type LokiLogger struct {
+	cfg     LokiConfig
+	client  *http.Client
+	entries chan lokiEntry
+	quit    chan struct{}
+	done    chan struct{}
+	once    sync.Once
+	// mu is held for reading while queuing a line, so that no line is queued once closed and drained.
+	mu     sync.RWMutex
+	closed bool
+	// ctx aborts the pending retries once Close gave up.
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+type lokiEntry struct {
+	labels model.LabelSet
+	entry  push.Entry
+}
+
+// NewLokiLogger creates a logger pushing to Loki, and starts its batching loop.
+func NewLokiLogger(cfg LokiConfig) (*LokiLogger, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("no Loki URL")
+	}
+	if cfg.Encoding != ProtobufEncoding && cfg.Encoding != JSONEncoding {
+		return nil, fmt.Errorf("unknown encoding %q", cfg.Encoding)
+	}
+	if cfg.BatchSize <= 0 || cfg.BatchWait <= 0 {
+		return nil, errors.New("batch size and wait must be positive")
+	}
+
+	l := &LokiLogger{
+		cfg:     cfg,
+		client:  &http.Client{Timeout: cfg.Timeout},
+		entries: make(chan lokiEntry, 1024),
+		quit:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	l.ctx, l.cancel = context.WithCancel(context.Background())
+	go l.run()
+	return l, nil
+}
+
+// Handle implements the Logger interface
+func (l *LokiLogger) Handle(labels model.LabelSet, timestamp time.Time, message string) error {
+	return l.HandleWithMetadata(labels, timestamp, message, nil)
+}
+
+// HandleWithMetadata implements the Logger interface, queuing the line for the next batch.
+func (l *LokiLogger) HandleWithMetadata(labels model.LabelSet, timestamp time.Time, message string, metadata push.LabelsAdapter) error {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.closed {
+		return ErrLoggerClosed
+	}
+	// The batching loop keeps reading until closed, which waits for this line to be queued.
+	l.entries <- lokiEntry{labels: labels, entry: push.Entry{Timestamp: timestamp, Line: message, StructuredMetadata: metadata}}
+	return nil
+}
+
+// Close implements the Logger interface, pushing the last batch. Retries are abandoned once ctx is done.
+func (l *LokiLogger) Close(ctx context.Context) error {
+	// Closing waits for the lines being queued, which wait for a push of the batching loop when the queue is full.
+	go l.once.Do(func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.closed = true
+		close(l.quit)
+	})
+	select {
+	case <-l.done:
+		return nil
+	case <-ctx.Done():
+		l.cancel()
+		return ctx.Err()
+	}
+}
+
+func (l *LokiLogger) run() {
+	defer close(l.done)
+	defer l.cancel()
+
+	// Batches are checked twice per wait, and at most every millisecond.
+	ticker := time.NewTicker(max(l.cfg.BatchWait/2, time.Millisecond))
+	defer ticker.Stop()
+
+	batch := newLokiBatch()
+	for {
+		select {
+		case e := <-l.entries:
+			if !batch.empty() && batch.size+e.size() > l.cfg.BatchSize {
+				l.send(batch)
+				batch = newLokiBatch()
+			}
+			batch.add(e)
+		case <-ticker.C:
+			if !batch.empty() && time.Since(batch.created) >= l.cfg.BatchWait {
+				l.send(batch)
+				batch = newLokiBatch()
+			}
+		case <-l.quit:
+		drain:
+			for {
+				select {
+				case e := <-l.entries:
+					batch.add(e)
+				default:
+					break drain
+				}
+			}
+			if !batch.empty() {
+				l.send(batch)
+			}
+			return
+		}
+	}
+}
+
+// send pushes the batch, retrying it as configured.
+func (l *LokiLogger) send(batch *lokiBatch) {
+	body, contentType, contentEncoding, err := batch.encode(l.cfg.Encoding, l.cfg.Gzip)
+	if err != nil {
+		l.failed(batch, err)
+		return
+	}
+
+	err = retry(l.ctx, l.cfg.MinBackoff, l.cfg.MaxBackoff, l.cfg.MaxRetries, func() error {
+		return l.push(body, contentType, contentEncoding)
+	})
+	if err != nil {
+		l.failed(batch, err)
+	}
+}
+
+// retry calls push until it succeeds, fails with an error that is not a retryable PushError, or failed maxRetries
+// more times. It waits for the Retry-After of 429 responses or a jittered exponential backoff between minBackoff and
+// maxBackoff, and returns the last error once ctx is done.
+func retry(ctx context.Context, minBackoff, maxBackoff time.Duration, maxRetries int, push func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := push()
+		if err == nil {
+			return nil
+		}
+		var pushErr *PushError
+		if !errors.As(err, &pushErr) || !pushErr.retryable() || attempt >= maxRetries {
+			return err
+		}
+
+		wait := pushErr.RetryAfter
+		if wait == 0 {
+			wait = backoff(minBackoff, maxBackoff, attempt)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+	}
+}
+
+// backoff doubles min at every attempt up to max, with jitter so that clients do not retry all at once.
+func backoff(min, max time.Duration, attempt int) time.Duration {
+	d := min << attempt
+	if d <= 0 || d > max {
+		d = max
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+func (l *LokiLogger) push(body []byte, contentType, contentEncoding string) error {
+	req, err := http.NewRequestWithContext(l.ctx, http.MethodPost, l.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("User-Agent", "explore-logs-generator")
+	if contentEncoding != "" {
+		req.Header.Set("Content-Encoding", contentEncoding)
+	}
+	if l.cfg.TenantID != "" {
+		req.Header.Set("X-Scope-OrgID", l.cfg.TenantID)
+	}
+	if l.cfg.Password != "" {
+		req.SetBasicAuth(l.cfg.Username, l.cfg.Password)
+	}
+
+	start := time.Now()
+	resp, err := l.client.Do(req)
+	if err != nil {
+		l.observe(start)
+		return &PushError{Err: err}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 == 2 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		l.observe(start)
+		return nil
+	}
+
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	l.observe(start)
+	return &PushError{
+		StatusCode: resp.StatusCode,
+		Message:    strings.TrimSpace(string(message)),
+		RetryAfter: retryAfter(resp.Header.Get("Retry-After")),
+	}
+}
+
+func (l *LokiLogger) observe(start time.Time) {
+	if l.cfg.OnPush != nil {
+		l.cfg.OnPush(time.Since(start))
+	}
+}
+
+// retryAfter parses a Retry-After header, in seconds or as an HTTP date.
+func retryAfter(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(header); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// failed reports the streams of a batch that was not ingested. Loki names the offending streams of a rejected
+// batch in its response, only those are reported when it does.
+func (l *LokiLogger) failed(batch *lokiBatch, err error) {
+	streams := batch.streams
+	var pushErr *PushError
+	if errors.As(err, &pushErr) && pushErr.Message != "" {
+		var named []*lokiStream
+		for _, s := range batch.streams {
+			if strings.Contains(pushErr.Message, s.key) {
+				named = append(named, s)
+			}
+		}
+		if len(named) > 0 {
+			streams = named
+		}
+	}
+
+	log.Printf("Failed to push %d lines of %d streams to %s: %s", batch.entries, len(streams), l.cfg.URL, err)
+	if l.cfg.OnStreamError == nil {
+		return
+	}
+	for _, s := range streams {
+		l.cfg.OnStreamError(s.key, len(s.entries), err)
+	}
+}
+
+func (e lokiEntry) size() int {
+	size := len(e.entry.Line)
+	for _, m := range e.entry.StructuredMetadata {
+		size += len(m.Name) + len(m.Value)
+	}
+	return size
+}
+
+type lokiBatch struct {
+	streams []*lokiStream
+	byKey   map[string]*lokiStream
+	size    int
+	entries int
+	created time.Time
+}
+
+type lokiStream struct {
+	key     string
+	labels  model.LabelSet
+	entries []push.Entry
+}
+
+func newLokiBatch() *lokiBatch {
+	return &lokiBatch{byKey: map[string]*lokiStream{}, created: time.Now()}
+}
+
+func (b *lokiBatch) empty() bool {
+	return b.entries == 0
+}
+
+func (b *lokiBatch) add(e lokiEntry) {
+	key := e.labels.String()
+	s, ok := b.byKey[key]
+	if !ok {
+		s = &lokiStream{key: key, labels: e.labels}
+		b.byKey[key] = s
+		b.streams = append(b.streams, s)
+	}
+	s.entries = append(s.entries, e.entry)
+	b.size += e.size()
+	b.entries++
+}
+
+// encode returns the payload of the batch, with its Content-Type and Content-Encoding.
+func (b *lokiBatch) encode(encoding string, gzipped bool) ([]byte, string, string, error) {
+	if encoding == ProtobufEncoding {
+		req := push.PushRequest{Streams: make([]push.Stream, 0, len(b.streams))}
+		for _, s := range b.streams {
+			req.Streams = append(req.Streams, push.Stream{Labels: s.key, Entries: s.entries})
+		}
+		buf, err := req.Marshal()
+		if err != nil {
+			return nil, "", "", err
+		}
+		return snappy.Encode(nil, buf), "application/x-protobuf", "", nil
+	}
+
+	body, err := json.Marshal(b.jsonPayload())
+	if err != nil {
+		return nil, "", "", err
+	}
+	if !gzipped {
+		return body, "application/json", "", nil
+	}
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(body); err != nil {
+		return nil, "", "", err
+	}
+	if err := w.Close(); err != nil {
+		return nil, "", "", err
+	}
+	return buf.Bytes(), "application/json", "gzip", nil
+}
+
+type jsonPushRequest struct {
+	Streams []jsonStream `json:"streams"`
+}
+
+type jsonStream struct {
+	Stream model.LabelSet `json:"stream"`
+	// Values are [timestamp, line] or [timestamp, line, structured metadata] tuples.
+	Values [][]any `json:"values"`
+}
+
+func (b *lokiBatch) jsonPayload() jsonPushRequest {
+	req := jsonPushRequest{Streams: make([]jsonStream, 0, len(b.streams))}
+	for _, s := range b.streams {
+		stream := jsonStream{Stream: s.labels, Values: make([][]any, 0, len(s.entries))}
+		for _, e := range s.entries {
+			value := []any{strconv.FormatInt(e.Timestamp.UnixNano(), 10), e.Line}
+			if len(e.StructuredMetadata) > 0 {
+				metadata := make(map[string]string, len(e.StructuredMetadata))
+				for _, m := range e.StructuredMetadata {
+					metadata[m.Name] = m.Value
+				}
+				value = append(value, metadata)
+			}
+			stream.Values = append(stream.Values, value)
+		}
+		req.Streams = append(req.Streams, stream)
+	}
+	return req
+}
@@ -0,0 +1,212 @@
+package log
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/grafana/loki/pkg/push"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testLokiConfig(url string) LokiConfig {
+	cfg := DefaultLokiConfig(url)
+	cfg.BatchWait = 10 * time.Millisecond
+	cfg.MinBackoff = time.Millisecond
+	cfg.MaxBackoff = 5 * time.Millisecond
+	return cfg
+}
+
+func TestLokiLoggerProtobuf(t *testing.T) {
+	var (
+		mu  sync.Mutex
+		req push.PushRequest
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+		assert.Equal(t, "29", r.Header.Get("X-Scope-OrgID"))
+		compressed, _ := io.ReadAll(r.Body)
+		body, err := snappy.Decode(nil, compressed)
+		require.NoError(t, err)
+		mu.Lock()
+		defer mu.Unlock()
+		require.NoError(t, req.Unmarshal(body))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	cfg := testLokiConfig(srv.URL)
+	cfg.TenantID = "29"
+	logger, err := NewLokiLogger(cfg)
+	require.NoError(t, err)
+
+	ts := time.Unix(0, 1700000000000000000)
+	labels := model.LabelSet{"service_name": "nginx", "level": "info"}
+	require.NoError(t, logger.HandleWithMetadata(labels, ts, "hello", push.LabelsAdapter{{Name: "traceID", Value: "abc"}}))
+	require.NoError(t, logger.Close(context.Background()))
+	assert.ErrorIs(t, logger.Handle(labels, ts, "too late"), ErrLoggerClosed)
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, req.Streams, 1)
+	assert.Equal(t, labels.String(), req.Streams[0].Labels)
+	require.Len(t, req.Streams[0].Entries, 1)
+	assert.Equal(t, "hello", req.Streams[0].Entries[0].Line)
+	assert.True(t, ts.Equal(req.Streams[0].Entries[0].Timestamp))
+	assert.Equal(t, push.LabelsAdapter{{Name: "traceID", Value: "abc"}}, req.Streams[0].Entries[0].StructuredMetadata)
+}
+
+func TestLokiLoggerCloseRace(t *testing.T) {
+	var received atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		compressed, _ := io.ReadAll(r.Body)
+		body, err := snappy.Decode(nil, compressed)
+		require.NoError(t, err)
+		var req push.PushRequest
+		require.NoError(t, req.Unmarshal(body))
+		for _, stream := range req.Streams {
+			received.Add(int32(len(stream.Entries)))
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	cfg := testLokiConfig(srv.URL)
+	// The smallest wait does not stop the batching loop.
+	cfg.BatchWait = time.Nanosecond
+	logger, err := NewLokiLogger(cfg)
+	require.NoError(t, err)
+
+	var (
+		accepted atomic.Int32
+		wg       sync.WaitGroup
+	)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				if err := logger.Handle(model.LabelSet{"service_name": "nginx"}, time.Now(), "hello"); err != nil {
+					assert.ErrorIs(t, err, ErrLoggerClosed)
+					return
+				}
+				accepted.Add(1)
+			}
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	require.NoError(t, logger.Close(context.Background()))
+	wg.Wait()
+
+	assert.Positive(t, accepted.Load())
+	assert.Equal(t, accepted.Load(), received.Load(), "every accepted line is pushed")
+}
+
+func TestLokiLoggerJSONGzip(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		body jsonPushRequest
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, "gzip", r.Header.Get("Content-Encoding"))
+		gz, err := gzip.NewReader(r.Body)
+		require.NoError(t, err)
+		mu.Lock()
+		defer mu.Unlock()
+		require.NoError(t, json.NewDecoder(gz).Decode(&body))
+	}))
+	defer srv.Close()
+
+	cfg := testLokiConfig(srv.URL)
+	cfg.Encoding = JSONEncoding
+	cfg.Gzip = true
+	logger, err := NewLokiLogger(cfg)
+	require.NoError(t, err)
+
+	labels := model.LabelSet{"service_name": "nginx"}
+	require.NoError(t, logger.HandleWithMetadata(labels, time.Unix(0, 42), "hello", push.LabelsAdapter{{Name: "pod", Value: "nginx-0"}}))
+	require.NoError(t, logger.Close(context.Background()))
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, body.Streams, 1)
+	assert.Equal(t, labels, body.Streams[0].Stream)
+	assert.Equal(t, [][]any{{"42", "hello", map[string]any{"pod": "nginx-0"}}}, body.Streams[0].Values)
+}
+
+func TestLokiLoggerRetries(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch calls.Add(1) {
+		case 1:
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer srv.Close()
+
+	var lost, pushes atomic.Int32
+	cfg := testLokiConfig(srv.URL)
+	cfg.OnStreamError = func(string, int, error) { lost.Add(1) }
+	cfg.OnPush = func(time.Duration) { pushes.Add(1) }
+	logger, err := NewLokiLogger(cfg)
+	require.NoError(t, err)
+	require.NoError(t, logger.Handle(model.LabelSet{"service_name": "nginx"}, time.Now(), "hello"))
+	require.NoError(t, logger.Close(context.Background()))
+
+	assert.Equal(t, int32(3), calls.Load())
+	assert.Equal(t, int32(3), pushes.Load(), "every request is timed")
+	assert.Zero(t, lost.Load())
+}
+
+func TestLokiLoggerStreamErrors(t *testing.T) {
+	bad := model.LabelSet{"service_name": "bad"}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "entry for stream '"+bad.String()+"' has timestamp too old", http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	var (
+		mu     sync.Mutex
+		failed []string
+		reason string
+	)
+	cfg := testLokiConfig(srv.URL)
+	cfg.OnStreamError = func(labels string, _ int, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		failed = append(failed, labels)
+		reason = ErrorReason(err)
+	}
+	logger, err := NewLokiLogger(cfg)
+	require.NoError(t, err)
+	require.NoError(t, logger.Handle(bad, time.Now(), "old"))
+	require.NoError(t, logger.Handle(model.LabelSet{"service_name": "good"}, time.Now(), "new"))
+	require.NoError(t, logger.Close(context.Background()))
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{bad.String()}, failed, "a 400 is not retried and only the named stream failed")
+	assert.Equal(t, "rejected", reason)
+}
+
+func TestRetryAfter(t *testing.T) {
+	assert.Equal(t, 3*time.Second, retryAfter("3"))
+	assert.Zero(t, retryAfter(""))
+	assert.Zero(t, retryAfter("soon"))
+	assert.InDelta(t, time.Minute, retryAfter(time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)), float64(2*time.Second))
+}
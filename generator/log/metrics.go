@@ -0,0 +1,124 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/grafana/loki/pkg/push"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+)
+
+// Metrics are the self-metrics of the generator, comparing them with the ingested volume tells what got lost on the way.
+type Metrics struct {
+	lines         *prometheus.CounterVec
+	bytes         *prometheus.CounterVec
+	errors        *prometheus.CounterVec
+	duration      *prometheus.HistogramVec
+	activeStreams *prometheus.GaugeVec
+}
+
+// NewMetrics creates the generator metrics and registers them with reg.
+func NewMetrics(reg prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		lines: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "generator_lines_total",
+			Help: "Lines handed to a sink.",
+		}, []string{"namespace", "service_name", "level", "sink"}),
+		bytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "generator_bytes_total",
+			Help: "Bytes of the lines handed to a sink.",
+		}, []string{"namespace", "service_name", "level", "sink"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "generator_push_errors_total",
+			Help: "Lines a sink failed to handle, by reason.",
+		}, []string{"sink", "reason"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "generator_push_duration_seconds",
+			Help:    "Time a push request of a sink took, a batch to Loki or an OTLP endpoint, or a syslog message.",
+			Buckets: prometheus.ExponentialBuckets(0.00005, 4, 10),
+		}, []string{"sink"}),
+		activeStreams: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "generator_active_streams",
+			Help: "Pod streams being generated.",
+		}, []string{"namespace", "service_name"}),
+	}
+	reg.MustRegister(m.lines, m.bytes, m.errors, m.duration, m.activeStreams)
+	return m
+}
+
+// SetActiveStreams records the number of streams generated for a service.
+func (m *Metrics) SetActiveStreams(namespace, service string, streams int) {
+	m.activeStreams.WithLabelValues(namespace, service).Set(float64(streams))
+}
+
+// PushFailed records lines a sink accepted, but failed to deliver afterwards.
+func (m *Metrics) PushFailed(sink string, lines int, err error) {
+	m.errors.WithLabelValues(sink, ErrorReason(err)).Add(float64(lines))
+}
+
+// ObservePush records the time a push request of a sink took.
+func (m *Metrics) ObservePush(sink string, d time.Duration) {
+	m.duration.WithLabelValues(sink).Observe(d.Seconds())
+}
+
+// Instrument wraps next, recording the lines it handles under the sink name.
+func (m *Metrics) Instrument(sink string, next Logger) Logger {
+	return &instrumentedLogger{
+		Metrics: m,
+		sink:    sink,
+		next:    next,
+	}
+}
+
+type instrumentedLogger struct {
+	*Metrics
+	sink string
+	next Logger
+}
+
+// Handle implements the Logger interface
+func (l *instrumentedLogger) Handle(labels model.LabelSet, timestamp time.Time, message string) error {
+	return l.HandleWithMetadata(labels, timestamp, message, nil)
+}
+
+// HandleWithMetadata implements the Logger interface
+func (l *instrumentedLogger) HandleWithMetadata(labels model.LabelSet, timestamp time.Time, message string, metadata push.LabelsAdapter) error {
+	// Sinks pushing in the background time their requests themselves, see ObservePush.
+	err := l.next.HandleWithMetadata(labels, timestamp, message, metadata)
+	if err != nil {
+		l.errors.WithLabelValues(l.sink, ErrorReason(err)).Inc()
+		return err
+	}
+
+	values := []string{string(labels["namespace"]), string(labels["service_name"]), string(labels["level"]), l.sink}
+	l.lines.WithLabelValues(values...).Inc()
+	l.bytes.WithLabelValues(values...).Add(float64(len(message)))
+	return nil
+}
+
+// Close implements the Logger interface
+func (l *instrumentedLogger) Close(ctx context.Context) error {
+	return l.next.Close(ctx)
+}
+
+// ErrorReason classifies err for the push errors metric. Errors implementing Reason() string name their own reason.
+func ErrorReason(err error) string {
+	var reasoner interface{ Reason() string }
+	if errors.As(err, &reasoner) {
+		return reasoner.Reason()
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return "timeout"
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return "timeout"
+		}
+		return "network"
+	}
+	return "other"
+}
@@ -0,0 +1,57 @@
+package log
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/grafana/loki/pkg/push"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstrumentedLogger(t *testing.T) {
+	m := NewMetrics(prometheus.NewRegistry())
+	fail := false
+	logger := m.Instrument("test", LoggerFunc(func(model.LabelSet, time.Time, string, push.LabelsAdapter) error {
+		if fail {
+			return &net.OpError{Op: "dial", Err: errors.New("connection refused")}
+		}
+		return nil
+	}))
+
+	labels := model.LabelSet{"namespace": "gateway", "service_name": "nginx", "level": "info"}
+	assert.NoError(t, logger.Handle(labels, time.Now(), "hello"))
+	assert.NoError(t, logger.Handle(labels, time.Now(), "world!"))
+	fail = true
+	assert.Error(t, logger.Handle(labels, time.Now(), "lost"))
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.lines.WithLabelValues("gateway", "nginx", "info", "test")))
+	assert.Equal(t, 11.0, testutil.ToFloat64(m.bytes.WithLabelValues("gateway", "nginx", "info", "test")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.errors.WithLabelValues("test", "network")))
+	assert.Zero(t, testutil.CollectAndCount(m.duration), "handing a line to a sink is not a push")
+
+	m.ObservePush("test", 20*time.Millisecond)
+	assert.Equal(t, 1, testutil.CollectAndCount(m.duration))
+}
+
+func TestSetActiveStreams(t *testing.T) {
+	m := NewMetrics(prometheus.NewRegistry())
+	m.SetActiveStreams("gateway", "nginx", 6)
+	m.SetActiveStreams("gateway", "nginx", 6)
+	assert.Equal(t, 6.0, testutil.ToFloat64(m.activeStreams.WithLabelValues("gateway", "nginx")), "setting up the streams again does not add them up")
+}
+
+func TestErrorReason(t *testing.T) {
+	assert.Equal(t, "other", ErrorReason(errors.New("boom")))
+	assert.Equal(t, "network", ErrorReason(&net.OpError{Op: "write", Err: errors.New("broken pipe")}))
+	assert.Equal(t, "rate_limited", ErrorReason(reasonError("rate_limited")))
+}
+
+type reasonError string
+
+func (e reasonError) Error() string  { return string(e) }
+func (e reasonError) Reason() string { return string(e) }
@@ -2,18 +2,27 @@ package log
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"hash/fnv"
 	"log/slog"
 	"os"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/grafana/loki/pkg/push"
 	"github.com/prometheus/common/model"
 	"go.opentelemetry.io/contrib/bridges/otelslog"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploggrpc"
 	sdk "go.opentelemetry.io/otel/sdk/log"
 	"go.opentelemetry.io/otel/sdk/resource"
 	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 )
@@ -21,17 +30,74 @@ import (
 // OtelLogger implements the Logger interface and provides OpenTelemetry context awareness
 type OtelLogger struct {
 	logger *slog.Logger
+	// export is the exporter of the logger, nil when it belongs to an OtelPool.
+	export *otelExport
 }
 
-// NewOtelLogger creates a new OpenTelemetry-aware logger
-func NewOtelLogger(svcName string, labels model.LabelSet) *OtelLogger {
-	provider, err := loggingProvider(svcName, labels)
+// NewOtelLogger creates a new OpenTelemetry-aware logger, exporting to a collector or straight to Loki per cfg.
+func NewOtelLogger(svcName string, labels model.LabelSet, cfg OTLPConfig) (*OtelLogger, error) {
+	export, err := newOtelExport(context.Background(), cfg, OTLPHooks{})
 	if err != nil {
-		return nil
+		return nil, err
+	}
+	logger, err := newOtelLogger(svcName, labels, nil, export)
+	if err != nil {
+		return nil, err
+	}
+	logger.export = export
+	return logger, nil
+}
+
+func newOtelLogger(svcName string, labels model.LabelSet, metadata push.LabelsAdapter, export *otelExport) (*OtelLogger, error) {
+	res, err := otelResource(svcName, labels, metadata)
+	if err != nil {
+		return nil, err
+	}
+	provider := sdk.NewLoggerProvider(sdk.WithResource(res), sdk.WithProcessor(export.processor))
+	return &OtelLogger{logger: otelslog.NewLogger("log-generator", otelslog.WithLoggerProvider(provider))}, nil
+}
+
+// OtelPool creates the OTel loggers of all the pods, sharing one exporter and batch processor, and so one
+// connection, per OTLP configuration. Each pod keeps its own resource.
+type OtelPool struct {
+	hooks OTLPHooks
+
+	mu      sync.Mutex
+	exports map[OTLPConfig]*otelExport
+}
+
+// NewOtelPool creates an empty pool, its exporters notify hooks.
+func NewOtelPool(hooks OTLPHooks) *OtelPool {
+	return &OtelPool{hooks: hooks, exports: map[OTLPConfig]*otelExport{}}
+}
+
+// NewLogger creates the logger of a pod of svcName, its resource describes the pod of the pod metadata. The logger
+// is closed with the pool.
+func (p *OtelPool) NewLogger(svcName string, labels model.LabelSet, metadata push.LabelsAdapter, cfg OTLPConfig) (*OtelLogger, error) {
+	p.mu.Lock()
+	export, ok := p.exports[cfg]
+	if !ok {
+		var err error
+		if export, err = newOtelExport(context.Background(), cfg, p.hooks); err != nil {
+			p.mu.Unlock()
+			return nil, err
+		}
+		p.exports[cfg] = export
 	}
-	return &OtelLogger{
-		logger: otelslog.NewLogger("log-generator", otelslog.WithLoggerProvider(provider)),
+	p.mu.Unlock()
+	return newOtelLogger(svcName, labels, metadata, export)
+}
+
+// Close exports the batched records of all the loggers and closes the connections.
+func (p *OtelPool) Close(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var errs []error
+	for cfg, export := range p.exports {
+		errs = append(errs, export.shutdown(ctx))
+		delete(p.exports, cfg)
 	}
+	return errors.Join(errs...)
 }
 
 // Handle implements the Logger interface
@@ -42,7 +108,7 @@ func (o *OtelLogger) Handle(labels model.LabelSet, timestamp time.Time, message
 // HandleWithMetadata implements the Logger interface with OpenTelemetry context
 func (o *OtelLogger) HandleWithMetadata(labels model.LabelSet, timestamp time.Time, message string, metadata push.LabelsAdapter) error {
 	// Convert labels to slog attributes
-	attrs := make([]slog.Attr, 0, len(labels)+3) // +3 for potential trace context
+	attrs := make([]slog.Attr, 0, len(labels)+len(metadata))
 
 	// Add all labels as attributes
 	for k, v := range labels {
@@ -55,17 +121,18 @@ func (o *OtelLogger) HandleWithMetadata(labels model.LabelSet, timestamp time.Ti
 		attrs = append(attrs, slog.String(string(k), string(v)))
 	}
 
-	//
-	// Add metadata if present
-	if metadata != nil {
-		for _, label := range metadata {
-			attrs = append(attrs, slog.String(label.Name, label.Value))
+	// Add metadata if present, the trace ID is the one of the span context.
+	for _, label := range metadata {
+		if label.Name == "traceID" {
+			continue
 		}
+		attrs = append(attrs, slog.String(label.Name, label.Value))
 	}
 
-	// Extract trace context if available
-	if traceID := extractTraceID(metadata); traceID != "" {
-		attrs = append(attrs, slog.String("trace_id", traceID))
+	// The SDK sets the trace ID, span ID and flags of the record from the span context.
+	ctx := context.Background()
+	if sc, ok := spanContext(extractTraceID(metadata), timestamp, message); ok {
+		ctx = trace.ContextWithSpanContext(ctx, sc)
 	}
 
 	// Determine log level from labels
@@ -76,7 +143,16 @@ func (o *OtelLogger) HandleWithMetadata(labels model.LabelSet, timestamp time.Ti
 	record.AddAttrs(attrs...)
 
 	// Log the record
-	return o.logger.Handler().Handle(context.Background(), record)
+	return o.logger.Handler().Handle(ctx, record)
+}
+
+// Close implements the Logger interface, exporting the batched records before shutting the exporter down. The
+// loggers of an OtelPool are closed with the pool.
+func (o *OtelLogger) Close(ctx context.Context) error {
+	if o.export == nil {
+		return nil
+	}
+	return o.export.shutdown(ctx)
 }
 
 // extractTraceID attempts to get trace ID from metadata
@@ -93,6 +169,34 @@ func extractTraceID(metadata push.LabelsAdapter) string {
 	return ""
 }
 
+// spanContext returns the sampled span context of a line of the trace traceID, a UUID or 32 hex digits, any other
+// value is hashed. Lines are spans of their own, with an ID derived from the line so that seeded runs export the
+// same IDs.
+func spanContext(traceID string, timestamp time.Time, message string) (trace.SpanContext, bool) {
+	if traceID == "" {
+		return trace.SpanContext{}, false
+	}
+
+	var tid trace.TraceID
+	if raw, err := hex.DecodeString(strings.ReplaceAll(traceID, "-", "")); err == nil && len(raw) == len(tid) {
+		copy(tid[:], raw)
+	} else {
+		sum := sha256.Sum256([]byte(traceID))
+		copy(tid[:], sum[:])
+	}
+
+	h := fnv.New64a()
+	_, _ = h.Write(tid[:])
+	_ = binary.Write(h, binary.BigEndian, timestamp.UnixNano())
+	_, _ = h.Write([]byte(message))
+	var sid trace.SpanID
+	binary.BigEndian.PutUint64(sid[:], h.Sum64())
+
+	sc := trace.NewSpanContext(trace.SpanContextConfig{TraceID: tid, SpanID: sid, TraceFlags: trace.FlagsSampled})
+	// All zero IDs are invalid.
+	return sc, sc.IsValid()
+}
+
 // getSlogLevel converts Loki log levels to slog levels
 func getSlogLevel(labels model.LabelSet) slog.Level {
 	if level, ok := labels["level"]; ok {
@@ -110,22 +214,8 @@ func getSlogLevel(labels model.LabelSet) slog.Level {
 	return slog.LevelInfo
 }
 
-func loggingProvider(svcName string, labels model.LabelSet) (*sdk.LoggerProvider, error) {
-	ctx := context.Background()
-
-	// Get collector endpoint from env var or use default
-	collectorEndpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
-	if collectorEndpoint == "" {
-		collectorEndpoint = "localhost:4317"
-	}
-
-	// Create gRPC connection to collector
-	conn, err := grpc.NewClient(collectorEndpoint, grpc.WithTransportCredentials(insecure.NewCredentials()))
-
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to collector: %w", err)
-	}
-
+// otelResource describes the service, and its pod when the metadata holds one.
+func otelResource(svcName string, labels model.LabelSet, metadata push.LabelsAdapter) (*resource.Resource, error) {
 	var cluster, namespace, env string
 	for k, v := range labels {
 		switch k {
@@ -139,27 +229,83 @@ func loggingProvider(svcName string, labels model.LabelSet) (*sdk.LoggerProvider
 	}
 
 	// Create resource with service information and indexed labels
-	res, err := resource.New(ctx,
-		resource.WithAttributes(
-			semconv.ServiceName(svcName),
-			semconv.ServiceVersion("1.0.0"),
-			semconv.K8SNamespaceName(namespace),
-			semconv.K8SClusterName(cluster),
-			semconv.DeploymentEnvironment(env),
-		),
-	)
+	attrs := []attribute.KeyValue{
+		semconv.ServiceName(svcName),
+		semconv.ServiceVersion("1.0.0"),
+		semconv.K8SNamespaceName(namespace),
+		semconv.K8SClusterName(cluster),
+		semconv.DeploymentEnvironment(env),
+	}
+	for _, label := range metadata {
+		if label.Name != "pod" {
+			continue
+		}
+		// Pods are spread over the nodes of their cluster, and their hostname is their name.
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(label.Value))
+		attrs = append(attrs,
+			semconv.K8SPodName(label.Value),
+			semconv.ServiceInstanceID(namespace+"."+label.Value),
+			semconv.HostName(label.Value),
+			semconv.K8SNodeName(fmt.Sprintf("%s-node-%d", cluster, h.Sum32()%otelNodes)),
+		)
+	}
+	res, err := resource.New(context.Background(), resource.WithAttributes(attrs...))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create resource: %w", err)
 	}
+	return res, nil
+}
 
-	// Create OTLP exporter
-	exporter, err := otlploggrpc.New(ctx,
-		otlploggrpc.WithGRPCConn(conn))
+// otelNodes is the number of nodes of a cluster.
+const otelNodes = 8
+
+// otelExport is an exporter behind its batch processor, shared by the providers of the loggers.
+type otelExport struct {
+	processor *sdk.BatchProcessor
+	// conn is the connection to the collector, nil over HTTP.
+	conn *grpc.ClientConn
+}
+
+func newOtelExport(ctx context.Context, cfg OTLPConfig, hooks OTLPHooks) (*otelExport, error) {
+	if cfg.Protocol != "" && cfg.Protocol != OTLPGRPC {
+		exporter, err := newOTLPHTTPExporter(ctx, cfg, hooks.OnExport)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create log exporter: %w", err)
+		}
+		// Like the gRPC exporter, the SDK's protobuf exporter retries within an export, which is timed as a whole.
+		timed := cfg.Protocol == OTLPHTTPProtobuf
+		return &otelExport{processor: sdk.NewBatchProcessor(observedExporter{Exporter: exporter, hooks: hooks, timed: timed})}, nil
+	}
+
+	// Get collector endpoint from config, env var or use default
+	collectorEndpoint := cfg.Endpoint
+	if collectorEndpoint == "" {
+		collectorEndpoint = os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	}
+	if collectorEndpoint == "" {
+		collectorEndpoint = "localhost:4317"
+	}
+
+	// Create gRPC connection to collector
+	conn, err := grpc.NewClient(collectorEndpoint, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to collector: %w", err)
+	}
+	exporter, err := otlploggrpc.New(ctx, otlploggrpc.WithGRPCConn(conn))
 	if err != nil {
+		conn.Close()
 		return nil, fmt.Errorf("failed to create log exporter: %w", err)
 	}
-	proc := sdk.NewBatchProcessor(exporter)
+	// The gRPC exporter retries within an export, which is timed as a whole.
+	return &otelExport{processor: sdk.NewBatchProcessor(observedExporter{Exporter: exporter, hooks: hooks, timed: true}), conn: conn}, nil
+}
 
-	// Create logger provider
-	return sdk.NewLoggerProvider(sdk.WithResource(res), sdk.WithProcessor(proc)), nil
+// shutdown exports the batched records, then closes the exporter and its connection.
+func (e *otelExport) shutdown(ctx context.Context) error {
+	err := e.processor.Shutdown(ctx)
+	if e.conn != nil {
+		err = errors.Join(err, e.conn.Close())
+	}
+	return err
 }
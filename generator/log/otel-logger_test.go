@@ -0,0 +1,100 @@
+package log
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafana/loki/pkg/push"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/contrib/bridges/otelslog"
+	otellog "go.opentelemetry.io/otel/log"
+	sdk "go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type recordingExporter struct {
+	mu      sync.Mutex
+	records []sdk.Record
+}
+
+func (e *recordingExporter) Export(_ context.Context, records []sdk.Record) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, r := range records {
+		e.records = append(e.records, r.Clone())
+	}
+	return nil
+}
+
+func (e *recordingExporter) Shutdown(context.Context) error   { return nil }
+func (e *recordingExporter) ForceFlush(context.Context) error { return nil }
+
+func TestOtelPool(t *testing.T) {
+	exporter := &recordingExporter{}
+	pool := NewOtelPool(OTLPHooks{})
+	cfg := OTLPConfig{Protocol: OTLPHTTPProtobuf, Endpoint: "http://localhost/otlp/v1/logs"}
+	// Export to exporter instead of the endpoint.
+	pool.exports[cfg] = &otelExport{processor: sdk.NewBatchProcessor(exporter)}
+
+	labels := model.LabelSet{"cluster": "us-east-1", "namespace": "shop", "service_name": "checkout"}
+	for _, pod := range []string{"checkout-a", "checkout-b"} {
+		logger, err := pool.NewLogger("checkout", labels, push.LabelsAdapter{{Name: "pod", Value: pod}}, cfg)
+		require.NoError(t, err)
+		require.NoError(t, logger.Handle(labels, time.Now(), "hello"))
+		require.NoError(t, logger.Close(context.Background()), "a pooled logger is closed with the pool")
+	}
+	assert.Len(t, pool.exports, 1, "the pods share the exporter")
+	require.NoError(t, pool.Close(context.Background()))
+
+	require.Len(t, exporter.records, 2)
+	var pods []string
+	for _, r := range exporter.records {
+		res := r.Resource()
+		attrs := map[string]string{}
+		for _, kv := range res.Attributes() {
+			attrs[string(kv.Key)] = kv.Value.Emit()
+		}
+		pod := attrs["k8s.pod.name"]
+		pods = append(pods, pod)
+		assert.Equal(t, pod, attrs["host.name"])
+		assert.Equal(t, "shop."+pod, attrs["service.instance.id"])
+		assert.Regexp(t, `^us-east-1-node-\d$`, attrs["k8s.node.name"])
+	}
+	assert.Equal(t, []string{"checkout-a", "checkout-b"}, pods)
+}
+
+func TestOtelLoggerSpanContext(t *testing.T) {
+	exporter := &recordingExporter{}
+	provider := sdk.NewLoggerProvider(sdk.WithProcessor(sdk.NewSimpleProcessor(exporter)))
+	logger := &OtelLogger{logger: otelslog.NewLogger("test", otelslog.WithLoggerProvider(provider))}
+
+	labels := model.LabelSet{"service_name": "checkout", "level": "info"}
+	metadata := push.LabelsAdapter{{Name: "traceID", Value: "5b8efff7-9803-8103-d269-b633813fc60c"}, {Name: "pod", Value: "checkout-0"}}
+	ts := time.Unix(0, 1700000000000000000)
+	require.NoError(t, logger.HandleWithMetadata(labels, ts, "first", metadata))
+	require.NoError(t, logger.HandleWithMetadata(labels, ts, "second", metadata))
+	require.NoError(t, logger.HandleWithMetadata(labels, ts, "no trace", nil))
+	require.NoError(t, logger.Close(context.Background()))
+
+	require.Len(t, exporter.records, 3)
+	first, second := exporter.records[0], exporter.records[1]
+	assert.Equal(t, "5b8efff798038103d269b633813fc60c", first.TraceID().String())
+	assert.Equal(t, first.TraceID(), second.TraceID())
+	assert.True(t, first.SpanID().IsValid())
+	assert.NotEqual(t, first.SpanID(), second.SpanID(), "every line is a span")
+	assert.Equal(t, trace.FlagsSampled, first.TraceFlags())
+	first.WalkAttributes(func(kv otellog.KeyValue) bool {
+		assert.NotEqual(t, "traceID", kv.Key, "the trace ID is not an attribute")
+		return true
+	})
+	assert.False(t, exporter.records[2].TraceID().IsValid())
+
+	sc, ok := spanContext("not-a-uuid", ts, "first")
+	assert.True(t, ok)
+	again, _ := spanContext("not-a-uuid", ts, "first")
+	assert.Equal(t, sc, again, "IDs are derived from the line")
+}
@@ -0,0 +1,377 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
+	api "go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	sdk "go.opentelemetry.io/otel/sdk/log"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	cpb "go.opentelemetry.io/proto/otlp/common/v1"
+	lpb "go.opentelemetry.io/proto/otlp/logs/v1"
+	rpb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// OTLP protocols, named after the values of OTEL_EXPORTER_OTLP_PROTOCOL.
+const (
+	OTLPGRPC         = "grpc"
+	OTLPHTTPProtobuf = "http/protobuf"
+	OTLPHTTPJSON     = "http/json"
+)
+
+// OTLPConfig configures the export of the logs of the OTel services.
+type OTLPConfig struct {
+	// Protocol is OTLPGRPC, to a collector, or OTLPHTTPProtobuf and OTLPHTTPJSON, which Loki ingests directly.
+	Protocol string
+	// Endpoint is the host:port of the gRPC collector, OTEL_EXPORTER_OTLP_ENDPOINT or localhost:4317 when unset,
+	// or the URL of the OTLP/HTTP logs, e.g. http://localhost:3100/otlp/v1/logs.
+	Endpoint string
+	// TenantID, Username and Password are sent over HTTP, like to the Loki push API.
+	TenantID string
+	Username string
+	Password string
+	// Timeout of a single export over HTTP.
+	Timeout time.Duration
+	// An export over HTTP failing with a 429, a 5xx or a network error is retried up to MaxRetries times, like a
+	// Loki push. The protobuf exporter of the SDK retries the 429, 502, 503 and 504 responses.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	MaxRetries int
+}
+
+// OTLPHooks are notified of the exports of the OTel loggers. They are kept out of OTLPConfig, which keys the
+// exporters of an OtelPool.
+type OTLPHooks struct {
+	// OnExport is called with the time every export request took.
+	OnExport func(d time.Duration)
+	// OnExportError is called with the number of records a failed export lost.
+	OnExportError func(records int, err error)
+}
+
+// observedExporter reports the failed exports of the wrapped exporter, which the batch processor only logs.
+type observedExporter struct {
+	sdk.Exporter
+	hooks OTLPHooks
+	// timed times the exports, for exporters not timing their requests themselves.
+	timed bool
+}
+
+// Export implements sdk.Exporter.
+func (e observedExporter) Export(ctx context.Context, records []sdk.Record) error {
+	start := time.Now()
+	err := e.Exporter.Export(ctx, records)
+	if e.timed && e.hooks.OnExport != nil {
+		e.hooks.OnExport(time.Since(start))
+	}
+	if err != nil && e.hooks.OnExportError != nil {
+		e.hooks.OnExportError(len(records), err)
+	}
+	return err
+}
+
+// newOTLPHTTPExporter exports records to an OTLP/HTTP endpoint, encoded as protobuf by the SDK exporter, or as JSON,
+// which it does not support.
+func newOTLPHTTPExporter(ctx context.Context, cfg OTLPConfig, onExport func(d time.Duration)) (sdk.Exporter, error) {
+	if cfg.Endpoint == "" {
+		return nil, fmt.Errorf("no OTLP/HTTP endpoint")
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	switch cfg.Protocol {
+	case OTLPHTTPProtobuf:
+		return otlploghttp.New(ctx, cfg.otlploghttpOptions()...)
+	case OTLPHTTPJSON:
+		return &otlpJSONExporter{cfg: cfg, client: &http.Client{Timeout: cfg.Timeout}, onExport: onExport}, nil
+	}
+	return nil, fmt.Errorf("%s is not an OTLP/HTTP protocol", cfg.Protocol)
+}
+
+// otlploghttpOptions configures the SDK exporter like an otlpJSONExporter.
+func (c OTLPConfig) otlploghttpOptions() []otlploghttp.Option {
+	headers := map[string]string{}
+	if c.TenantID != "" {
+		headers["X-Scope-OrgID"] = c.TenantID
+	}
+	if c.Password != "" {
+		headers["Authorization"] = "Basic " + base64.StdEncoding.EncodeToString([]byte(c.Username+":"+c.Password))
+	}
+
+	// The SDK exporter retries for a time, rather than a number of times: twice as long as MaxRetries backoffs, for
+	// their jitter and the requests.
+	var elapsed time.Duration
+	for attempt := range c.MaxRetries {
+		elapsed += 2 * min(c.MinBackoff<<attempt, c.MaxBackoff)
+	}
+	return []otlploghttp.Option{
+		otlploghttp.WithEndpointURL(c.Endpoint),
+		otlploghttp.WithHeaders(headers),
+		otlploghttp.WithTimeout(c.Timeout),
+		otlploghttp.WithRetry(otlploghttp.RetryConfig{
+			Enabled:         elapsed > 0,
+			InitialInterval: c.MinBackoff,
+			MaxInterval:     c.MaxBackoff,
+			MaxElapsedTime:  elapsed,
+		}),
+	}
+}
+
+// otlpJSONExporter exports records to an OTLP/HTTP endpoint encoded as JSON.
+type otlpJSONExporter struct {
+	cfg    OTLPConfig
+	client *http.Client
+	// onExport is called with the time every request took.
+	onExport func(d time.Duration)
+}
+
+// Export implements sdk.Exporter. The records are only valid during the call, they are encoded right away and the
+// body is sent again on retries.
+func (e *otlpJSONExporter) Export(ctx context.Context, records []sdk.Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+	body, err := otlpJSON(&collogspb.ExportLogsServiceRequest{ResourceLogs: resourceLogs(records)})
+	if err != nil {
+		return err
+	}
+	return retry(ctx, e.cfg.MinBackoff, e.cfg.MaxBackoff, e.cfg.MaxRetries, func() error {
+		return e.post(ctx, body)
+	})
+}
+
+// post sends a single export request.
+func (e *otlpJSONExporter) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.cfg.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "explore-logs-generator")
+	if e.cfg.TenantID != "" {
+		req.Header.Set("X-Scope-OrgID", e.cfg.TenantID)
+	}
+	if e.cfg.Password != "" {
+		req.SetBasicAuth(e.cfg.Username, e.cfg.Password)
+	}
+
+	start := time.Now()
+	resp, err := e.client.Do(req)
+	if err != nil {
+		e.observe(start)
+		return &PushError{Err: err}
+	}
+	defer resp.Body.Close()
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	e.observe(start)
+	if resp.StatusCode/100 != 2 {
+		return &PushError{
+			StatusCode: resp.StatusCode,
+			Message:    strings.TrimSpace(string(message)),
+			RetryAfter: retryAfter(resp.Header.Get("Retry-After")),
+		}
+	}
+	return nil
+}
+
+func (e *otlpJSONExporter) observe(start time.Time) {
+	if e.onExport != nil {
+		e.onExport(time.Since(start))
+	}
+}
+
+// Shutdown implements sdk.Exporter, exports are synchronous.
+func (e *otlpJSONExporter) Shutdown(context.Context) error {
+	return nil
+}
+
+// ForceFlush implements sdk.Exporter, exports are synchronous.
+func (e *otlpJSONExporter) ForceFlush(context.Context) error {
+	return nil
+}
+
+// otlpJSON encodes msg as OTLP/JSON, which differs from the canonical protobuf JSON mapping: trace and span IDs
+// are hex, not base64, and enums are numbers.
+func otlpJSON(msg *collogspb.ExportLogsServiceRequest) ([]byte, error) {
+	data, err := protojson.MarshalOptions{UseEnumNumbers: true}.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	for _, rl := range jsonArray(doc["resourceLogs"]) {
+		for _, sl := range jsonArray(rl["scopeLogs"]) {
+			for _, record := range jsonArray(sl["logRecords"]) {
+				for _, key := range []string{"traceId", "spanId"} {
+					if id, ok := record[key].(string); ok {
+						raw, err := base64.StdEncoding.DecodeString(id)
+						if err != nil {
+							return nil, err
+						}
+						record[key] = hex.EncodeToString(raw)
+					}
+				}
+			}
+		}
+	}
+	return json.Marshal(doc)
+}
+
+func jsonArray(v any) []map[string]any {
+	items, _ := v.([]any)
+	objects := make([]map[string]any, 0, len(items))
+	for _, item := range items {
+		if object, ok := item.(map[string]any); ok {
+			objects = append(objects, object)
+		}
+	}
+	return objects
+}
+
+// resourceLogs groups records by resource and scope, like the OTLP exporters of the SDK.
+func resourceLogs(records []sdk.Record) []*lpb.ResourceLogs {
+	type scopeKey struct {
+		resource attribute.Distinct
+		scope    instrumentation.Scope
+	}
+	var (
+		out       []*lpb.ResourceLogs
+		resources = map[attribute.Distinct]*lpb.ResourceLogs{}
+		scopes    = map[scopeKey]*lpb.ScopeLogs{}
+	)
+	for _, r := range records {
+		res := r.Resource()
+		rl, ok := resources[res.Equivalent()]
+		if !ok {
+			rl = &lpb.ResourceLogs{SchemaUrl: res.SchemaURL()}
+			if res.Len() > 0 {
+				rl.Resource = &rpb.Resource{Attributes: attrs(res.Iter())}
+			}
+			resources[res.Equivalent()] = rl
+			out = append(out, rl)
+		}
+
+		scope := r.InstrumentationScope()
+		key := scopeKey{resource: res.Equivalent(), scope: scope}
+		sl, ok := scopes[key]
+		if !ok {
+			sl = &lpb.ScopeLogs{SchemaUrl: scope.SchemaURL}
+			if scope != (instrumentation.Scope{}) {
+				sl.Scope = &cpb.InstrumentationScope{Name: scope.Name, Version: scope.Version, Attributes: attrs(scope.Attributes.Iter())}
+			}
+			scopes[key] = sl
+			rl.ScopeLogs = append(rl.ScopeLogs, sl)
+		}
+		sl.LogRecords = append(sl.LogRecords, logRecord(r))
+	}
+	return out
+}
+
+func logRecord(r sdk.Record) *lpb.LogRecord {
+	record := &lpb.LogRecord{
+		TimeUnixNano:         unixNano(r.Timestamp()),
+		ObservedTimeUnixNano: unixNano(r.ObservedTimestamp()),
+		// The API severities are the OTLP severity numbers.
+		SeverityNumber: lpb.SeverityNumber(r.Severity()),
+		SeverityText:   r.SeverityText(),
+		Body:           anyValue(r.Body()),
+		Flags:          uint32(r.TraceFlags()),
+	}
+	r.WalkAttributes(func(kv api.KeyValue) bool {
+		record.Attributes = append(record.Attributes, &cpb.KeyValue{Key: kv.Key, Value: anyValue(kv.Value)})
+		return true
+	})
+	if traceID := r.TraceID(); traceID.IsValid() {
+		record.TraceId = traceID[:]
+	}
+	if spanID := r.SpanID(); spanID.IsValid() {
+		record.SpanId = spanID[:]
+	}
+	return record
+}
+
+func unixNano(t time.Time) uint64 {
+	if t.IsZero() || t.UnixNano() < 0 {
+		return 0
+	}
+	return uint64(t.UnixNano())
+}
+
+func attrs(iter attribute.Iterator) []*cpb.KeyValue {
+	var out []*cpb.KeyValue
+	for iter.Next() {
+		kv := iter.Attribute()
+		out = append(out, &cpb.KeyValue{Key: string(kv.Key), Value: anyValue(logValue(kv.Value))})
+	}
+	return out
+}
+
+// logValue converts a resource or scope attribute to a log value.
+func logValue(v attribute.Value) api.Value {
+	switch v.Type() {
+	case attribute.BOOL:
+		return api.BoolValue(v.AsBool())
+	case attribute.INT64:
+		return api.Int64Value(v.AsInt64())
+	case attribute.FLOAT64:
+		return api.Float64Value(v.AsFloat64())
+	case attribute.BOOLSLICE:
+		return sliceValue(v.AsBoolSlice(), api.BoolValue)
+	case attribute.INT64SLICE:
+		return sliceValue(v.AsInt64Slice(), api.Int64Value)
+	case attribute.FLOAT64SLICE:
+		return sliceValue(v.AsFloat64Slice(), api.Float64Value)
+	case attribute.STRINGSLICE:
+		return sliceValue(v.AsStringSlice(), api.StringValue)
+	}
+	return api.StringValue(v.Emit())
+}
+
+func sliceValue[T any](items []T, value func(T) api.Value) api.Value {
+	values := make([]api.Value, 0, len(items))
+	for _, item := range items {
+		values = append(values, value(item))
+	}
+	return api.SliceValue(values...)
+}
+
+func anyValue(v api.Value) *cpb.AnyValue {
+	switch v.Kind() {
+	case api.KindBool:
+		return &cpb.AnyValue{Value: &cpb.AnyValue_BoolValue{BoolValue: v.AsBool()}}
+	case api.KindInt64:
+		return &cpb.AnyValue{Value: &cpb.AnyValue_IntValue{IntValue: v.AsInt64()}}
+	case api.KindFloat64:
+		return &cpb.AnyValue{Value: &cpb.AnyValue_DoubleValue{DoubleValue: v.AsFloat64()}}
+	case api.KindString:
+		return &cpb.AnyValue{Value: &cpb.AnyValue_StringValue{StringValue: v.AsString()}}
+	case api.KindBytes:
+		return &cpb.AnyValue{Value: &cpb.AnyValue_BytesValue{BytesValue: v.AsBytes()}}
+	case api.KindSlice:
+		values := make([]*cpb.AnyValue, 0, len(v.AsSlice()))
+		for _, item := range v.AsSlice() {
+			values = append(values, anyValue(item))
+		}
+		return &cpb.AnyValue{Value: &cpb.AnyValue_ArrayValue{ArrayValue: &cpb.ArrayValue{Values: values}}}
+	case api.KindMap:
+		values := make([]*cpb.KeyValue, 0, len(v.AsMap()))
+		for _, kv := range v.AsMap() {
+			values = append(values, &cpb.KeyValue{Key: kv.Key, Value: anyValue(kv.Value)})
+		}
+		return &cpb.AnyValue{Value: &cpb.AnyValue_KvlistValue{KvlistValue: &cpb.KeyValueList{Values: values}}}
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package log
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	lpb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestOtelLoggerOTLPHTTP(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		req  collogspb.ExportLogsServiceRequest
+		body map[string]any
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/otlp/v1/logs", r.URL.Path)
+		assert.Equal(t, "29", r.Header.Get("X-Scope-OrgID"))
+		username, password, _ := r.BasicAuth()
+		assert.Equal(t, "29:secret", username+":"+password)
+		data, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Header.Get("Content-Type") {
+		case "application/x-protobuf":
+			require.NoError(t, proto.Unmarshal(data, &req))
+		case "application/json":
+			require.NoError(t, json.Unmarshal(data, &body))
+		default:
+			t.Errorf("unexpected content type %s", r.Header.Get("Content-Type"))
+		}
+	}))
+	defer srv.Close()
+
+	labels := model.LabelSet{"cluster": "us-east-1", "namespace": "shop", "service_name": "checkout", "level": "warn"}
+	ts := time.Unix(0, 1700000000000000000)
+	for _, protocol := range []string{OTLPHTTPProtobuf, OTLPHTTPJSON} {
+		logger, err := NewOtelLogger("checkout", labels, OTLPConfig{Protocol: protocol, Endpoint: srv.URL + "/otlp/v1/logs", TenantID: "29", Username: "29", Password: "secret"})
+		require.NoError(t, err)
+		require.NoError(t, logger.Handle(labels, ts, "hello"))
+		require.NoError(t, logger.Close(context.Background()))
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, req.ResourceLogs, 1)
+	var service string
+	for _, attr := range req.ResourceLogs[0].Resource.Attributes {
+		if attr.Key == "service.name" {
+			service = attr.Value.GetStringValue()
+		}
+	}
+	assert.Equal(t, "checkout", service)
+	records := req.ResourceLogs[0].ScopeLogs[0].LogRecords
+	require.Len(t, records, 1)
+	assert.Equal(t, "hello", records[0].Body.GetStringValue())
+	assert.Equal(t, lpb.SeverityNumber_SEVERITY_NUMBER_WARN, records[0].SeverityNumber)
+	assert.Equal(t, uint64(ts.UnixNano()), records[0].TimeUnixNano)
+
+	record := body["resourceLogs"].([]any)[0].(map[string]any)["scopeLogs"].([]any)[0].(map[string]any)["logRecords"].([]any)[0].(map[string]any)
+	assert.Equal(t, float64(lpb.SeverityNumber_SEVERITY_NUMBER_WARN), record["severityNumber"], "OTLP/JSON enums are numbers")
+	assert.Equal(t, "hello", record["body"].(map[string]any)["stringValue"])
+}
+
+func TestOtelPoolHooks(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "stream limit exceeded", http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	var (
+		mu      sync.Mutex
+		exports int
+		lost    int
+		reason  string
+	)
+	pool := NewOtelPool(OTLPHooks{
+		OnExport: func(time.Duration) {
+			mu.Lock()
+			defer mu.Unlock()
+			exports++
+		},
+		OnExportError: func(records int, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			lost += records
+			reason = ErrorReason(err)
+		},
+	})
+	labels := model.LabelSet{"cluster": "us-east-1", "namespace": "shop", "service_name": "checkout"}
+	// The errors of the SDK's protobuf exporter are not classified.
+	logger, err := pool.NewLogger("checkout", labels, nil, OTLPConfig{Protocol: OTLPHTTPJSON, Endpoint: srv.URL})
+	require.NoError(t, err)
+	require.NoError(t, logger.Handle(labels, time.Now(), "hello"))
+	require.NoError(t, logger.Handle(labels, time.Now(), "world"))
+	assert.Error(t, pool.Close(context.Background()), "the last export failed")
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 1, exports)
+	assert.Equal(t, 2, lost)
+	assert.Equal(t, "rejected", reason)
+}
+
+func TestOTLPHTTPRetries(t *testing.T) {
+	for _, tc := range []struct {
+		protocol string
+		// exports is the number of timed exports, the SDK's protobuf exporter is timed as a whole with its retries.
+		exports int
+	}{
+		{OTLPHTTPProtobuf, 1},
+		{OTLPHTTPJSON, 3},
+	} {
+		t.Run(tc.protocol, func(t *testing.T) {
+			var (
+				mu                           sync.Mutex
+				requests, delivered, exports int
+				lost                         int
+			)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				requests++
+				switch requests {
+				case 1:
+					http.Error(w, "ingester unavailable", http.StatusServiceUnavailable)
+				case 2:
+					http.Error(w, "rate limited", http.StatusTooManyRequests)
+				default:
+					delivered++
+				}
+			}))
+			defer srv.Close()
+
+			pool := NewOtelPool(OTLPHooks{
+				OnExport: func(time.Duration) {
+					mu.Lock()
+					defer mu.Unlock()
+					exports++
+				},
+				OnExportError: func(n int, _ error) {
+					mu.Lock()
+					defer mu.Unlock()
+					lost += n
+				},
+			})
+			labels := model.LabelSet{"cluster": "us-east-1", "namespace": "shop", "service_name": "checkout"}
+			cfg := OTLPConfig{Protocol: tc.protocol, Endpoint: srv.URL, MinBackoff: 10 * time.Millisecond, MaxBackoff: 10 * time.Millisecond, MaxRetries: 2}
+			logger, err := pool.NewLogger("checkout", labels, nil, cfg)
+			require.NoError(t, err)
+			require.NoError(t, logger.Handle(labels, time.Now(), "hello"))
+			require.NoError(t, pool.Close(context.Background()))
+
+			mu.Lock()
+			defer mu.Unlock()
+			assert.Equal(t, 3, requests, "the 503 and the 429 are retried")
+			assert.Equal(t, 1, delivered)
+			assert.Equal(t, tc.exports, exports)
+			assert.Zero(t, lost)
+		})
+	}
+}
+
+func TestOTLPJSONHexIDs(t *testing.T) {
+	msg := &collogspb.ExportLogsServiceRequest{ResourceLogs: []*lpb.ResourceLogs{{ScopeLogs: []*lpb.ScopeLogs{{LogRecords: []*lpb.LogRecord{{
+		TraceId: []byte{0x5b, 0x8e, 0xff, 0xf7, 0x98, 0x03, 0x81, 0x03, 0xd2, 0x69, 0xb6, 0x33, 0x81, 0x3f, 0xc6, 0x0c},
+		SpanId:  []byte{0xee, 0xe1, 0x9b, 0x7e, 0xc3, 0xc1, 0xb1, 0x74},
+	}}}}}}}
+	data, err := otlpJSON(msg)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"traceId":"5b8efff798038103d269b633813fc60c"`)
+	assert.Contains(t, string(data), `"spanId":"eee19b7ec3c1b174"`)
+}
@@ -0,0 +1,212 @@
+package log
+
+import (
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/explore-logs/generator/flog"
+	"github.com/grafana/loki/pkg/push"
+	"github.com/prometheus/common/model"
+)
+
+// ContainerLogFormat is the format of the container runtime writing the logs of a pod.
+type ContainerLogFormat string
+
+const (
+	// CRIFormat is the format of containerd and CRI-O: <time> <stream> <P|F> <line>.
+	CRIFormat ContainerLogFormat = "cri"
+	// DockerFormat is the json-file format of Docker: {"log":"<line>\n","stream":"<stream>","time":"<time>"}.
+	DockerFormat ContainerLogFormat = "docker"
+)
+
+// DefaultMaxLineSize is the size above which the kubelet and Docker split a line into partial lines.
+const DefaultMaxLineSize = 16 * 1024
+
+// PodsConfig configures a PodsLogger.
+type PodsConfig struct {
+	// Dir is the pod log directory of the node, /var/log/pods on a real one.
+	Dir string
+	// Format is the format of the container runtime, CRIFormat or DockerFormat.
+	Format ContainerLogFormat
+	// MaxLineSize is the maximum size of the message of a log entry, longer lines are split into partial lines.
+	// DefaultMaxLineSize when unset.
+	MaxLineSize int
+	// ContainersDir, when set, gets the <pod>_<namespace>_<container>-<id>.log symlinks of /var/log/containers.
+	ContainersDir string
+}
+
+// PodsLogger implements the Logger interface and lays out the lines like the kubelet of a node does, in
+// <dir>/<namespace>_<pod>_<uid>/<container>/0.log files wrapped in the format of the container runtime.
+// The container is the service, the pod comes from the pod metadata.
+type PodsLogger struct {
+	cfg PodsConfig
+
+	mu    sync.Mutex
+	files map[string]*streamFile
+}
+
+// ParseContainerLogFormat validates the given format
+func ParseContainerLogFormat(format string) (ContainerLogFormat, error) {
+	switch f := ContainerLogFormat(format); f {
+	case CRIFormat, DockerFormat:
+		return f, nil
+	}
+	return "", fmt.Errorf("%s is not a valid container log format", format)
+}
+
+// NewPodsLogger creates a logger writing under cfg.Dir.
+func NewPodsLogger(cfg PodsConfig) *PodsLogger {
+	if cfg.MaxLineSize <= 0 {
+		cfg.MaxLineSize = DefaultMaxLineSize
+	}
+	return &PodsLogger{
+		cfg:   cfg,
+		files: map[string]*streamFile{},
+	}
+}
+
+// Handle implements the Logger interface
+func (l *PodsLogger) Handle(labels model.LabelSet, timestamp time.Time, message string) error {
+	return l.HandleWithMetadata(labels, timestamp, message, nil)
+}
+
+// HandleWithMetadata implements the Logger interface. Warnings and errors go to stderr, the other lines to stdout.
+func (l *PodsLogger) HandleWithMetadata(labels model.LabelSet, timestamp time.Time, message string, metadata push.LabelsAdapter) error {
+	file, err := l.file(labels, metadata)
+	if err != nil {
+		return err
+	}
+	stream := "stdout"
+	if labels["level"] == ERROR || labels["level"] == WARN {
+		stream = "stderr"
+	}
+	entries := l.entries(timestamp, stream, message)
+
+	file.mu.Lock()
+	defer file.mu.Unlock()
+	_, err = file.writer.Write(entries)
+	return err
+}
+
+// entries wraps message in log entries of the container runtime. Each line of message is an entry, split into
+// partial entries above the maximum line size.
+func (l *PodsLogger) entries(timestamp time.Time, stream, message string) []byte {
+	ts := timestamp.UTC().Format(time.RFC3339Nano)
+	var b []byte
+	for _, line := range strings.Split(message, "\n") {
+		for {
+			chunk, partial := line, false
+			if len(line) > l.cfg.MaxLineSize {
+				chunk, partial = line[:l.cfg.MaxLineSize], true
+			}
+			line = line[len(chunk):]
+			b = l.entry(b, ts, stream, chunk, partial)
+			if !partial {
+				break
+			}
+		}
+	}
+	return b
+}
+
+func (l *PodsLogger) entry(b []byte, ts, stream, chunk string, partial bool) []byte {
+	if l.cfg.Format == DockerFormat {
+		// Partial lines are the ones without a trailing newline.
+		if !partial {
+			chunk += "\n"
+		}
+		entry, _ := json.Marshal(struct {
+			Log    string `json:"log"`
+			Stream string `json:"stream"`
+			Time   string `json:"time"`
+		}{chunk, stream, ts})
+		return append(append(b, entry...), '\n')
+	}
+	tag := "F"
+	if partial {
+		tag = "P"
+	}
+	return append(b, ts+" "+stream+" "+tag+" "+chunk+"\n"...)
+}
+
+func (l *PodsLogger) file(labels model.LabelSet, metadata push.LabelsAdapter) (*streamFile, error) {
+	namespace := sanitizeFileName(string(labels["namespace"]))
+	container := sanitizeFileName(string(labels["service_name"]))
+	pod := ""
+	for _, label := range metadata {
+		if label.Name == "pod" {
+			pod = sanitizeFileName(label.Value)
+		}
+	}
+	if pod == "" {
+		// Services without metadata get a pod per cluster.
+		pod = container + "-" + sanitizeFileName(string(labels["cluster"]))
+	}
+	uid := podUID(namespace, pod)
+	path := filepath.Join(l.cfg.Dir, namespace+"_"+pod+"_"+uid, container, "0.log")
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if file, ok := l.files[path]; ok {
+		return file, nil
+	}
+	writer, err := flog.NewRotatingWriter(path, flog.RotateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if l.cfg.ContainersDir != "" {
+		if err := l.link(path, namespace, pod, uid, container); err != nil {
+			return nil, errors.Join(err, writer.Close())
+		}
+	}
+	file := &streamFile{writer: writer}
+	l.files[path] = file
+	return file, nil
+}
+
+// link adds the /var/log/containers symlink of the container log at path.
+func (l *PodsLogger) link(path, namespace, pod, uid, container string) error {
+	target, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(l.cfg.ContainersDir, 0o755); err != nil {
+		return err
+	}
+	id := sha256.Sum256([]byte(uid + "/" + container))
+	name := filepath.Join(l.cfg.ContainersDir, pod+"_"+namespace+"_"+container+"-"+hex.EncodeToString(id[:])+".log")
+	if err := os.Symlink(target, name); err != nil && !errors.Is(err, os.ErrExist) {
+		return err
+	}
+	return nil
+}
+
+// podUID derives a stable UID from the pod, so that a pod keeps its directory across runs.
+func podUID(namespace, pod string) string {
+	h := sha1.Sum([]byte(namespace + "/" + pod))
+	return fmt.Sprintf("%x-%x-%x-%x-%x", h[0:4], h[4:6], h[6:8], h[8:10], h[10:16])
+}
+
+// Close implements the Logger interface, closing every file.
+func (l *PodsLogger) Close(context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var errs []error
+	for path, file := range l.files {
+		file.mu.Lock()
+		errs = append(errs, file.writer.Close())
+		file.mu.Unlock()
+		delete(l.files, path)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,71 @@
+package log
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/loki/pkg/push"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPodsLoggerCRI(t *testing.T) {
+	dir, containers := t.TempDir(), t.TempDir()
+	logger := NewPodsLogger(PodsConfig{Dir: dir, Format: CRIFormat, MaxLineSize: 4, ContainersDir: containers})
+
+	labels := model.LabelSet{"namespace": "gateway", "service_name": "nginx", "cluster": "us-west-1"}
+	metadata := push.LabelsAdapter{{Name: "pod", Value: "nginx-x7k2p"}}
+	ts := time.Date(2024, 3, 5, 7, 8, 9, 123456789, time.FixedZone("CET", 3600))
+	require.NoError(t, logger.HandleWithMetadata(labels.Merge(model.LabelSet{"level": "info"}), ts, "abc", metadata))
+	require.NoError(t, logger.HandleWithMetadata(labels.Merge(model.LabelSet{"level": "error"}), ts, "abcdefghij\nk", metadata))
+	require.NoError(t, logger.Close(context.Background()))
+
+	path := filepath.Join(dir, "gateway_nginx-x7k2p_"+podUID("gateway", "nginx-x7k2p"), "nginx", "0.log")
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, `2024-03-05T06:08:09.123456789Z stdout F abc
+2024-03-05T06:08:09.123456789Z stderr P abcd
+2024-03-05T06:08:09.123456789Z stderr P efgh
+2024-03-05T06:08:09.123456789Z stderr F ij
+2024-03-05T06:08:09.123456789Z stderr F k
+`, string(data))
+
+	links, err := filepath.Glob(filepath.Join(containers, "nginx-x7k2p_gateway_nginx-*.log"))
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+	target, err := os.Readlink(links[0])
+	require.NoError(t, err)
+	assert.True(t, strings.HasSuffix(target, path))
+}
+
+func TestPodsLoggerDocker(t *testing.T) {
+	dir := t.TempDir()
+	logger := NewPodsLogger(PodsConfig{Dir: dir, Format: DockerFormat, MaxLineSize: 4})
+
+	labels := model.LabelSet{"namespace": "gateway", "service_name": "nginx", "cluster": "us-west-1", "level": "info"}
+	ts := time.Date(2024, 3, 5, 7, 8, 9, 0, time.UTC)
+	require.NoError(t, logger.Handle(labels, ts, `say "hello"`))
+	require.NoError(t, logger.Close(context.Background()))
+
+	data, err := os.ReadFile(filepath.Join(dir, "gateway_nginx-us-west-1_"+podUID("gateway", "nginx-us-west-1"), "nginx", "0.log"))
+	require.NoError(t, err)
+	var line string
+	for _, entry := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+		var e struct{ Log, Stream, Time string }
+		require.NoError(t, json.Unmarshal([]byte(entry), &e))
+		assert.Equal(t, "stdout", e.Stream)
+		assert.Equal(t, "2024-03-05T07:08:09Z", e.Time)
+		line += e.Log
+	}
+	assert.Equal(t, "say \"hello\"\n", line, "partial entries join into the line")
+	assert.Equal(t, 3, strings.Count(string(data), "\n"))
+
+	_, err = ParseContainerLogFormat("journald")
+	assert.Error(t, err)
+}
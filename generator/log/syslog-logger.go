@@ -1,25 +1,152 @@
 package log
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"hash/fnv"
+	"io"
 	"log/syslog"
 	"net"
 	"os"
+	"strconv"
+	"strings"
 	"time"
+	"unicode"
 
+	"github.com/grafana/explore-logs/generator/flog"
 	"github.com/grafana/loki/pkg/push"
 	"github.com/prometheus/common/model"
 )
 
+// SyslogFraming is how messages are delimited on a stream connection, see RFC6587.
+type SyslogFraming string
+
+const (
+	// NoFraming writes bare messages, one per datagram over UDP.
+	NoFraming SyslogFraming = ""
+	// OctetCountingFraming prefixes every message with its length in bytes and a space.
+	OctetCountingFraming SyslogFraming = "octet-counting"
+	// NonTransparentFraming ends every message with a LF, the LFs of multi-line messages are escaped as #012 like
+	// rsyslog does, so that they are not split.
+	NonTransparentFraming SyslogFraming = "non-transparent"
+)
+
+// ParseSyslogFraming validates the given framing
+func ParseSyslogFraming(framing string) (SyslogFraming, error) {
+	switch f := SyslogFraming(framing); f {
+	case NoFraming, OctetCountingFraming, NonTransparentFraming:
+		return f, nil
+	}
+	return "", fmt.Errorf("%s is not a valid syslog framing", framing)
+}
+
+// TLSConfig configures syslog over TLS, RFC5425.
+type TLSConfig struct {
+	// CAFile verifies the server certificate, the system roots are used when unset.
+	CAFile string
+	// CertFile and KeyFile are the client certificate, for collectors requiring mutual TLS.
+	CertFile string
+	KeyFile  string
+	// ServerName overrides the name verified in the server certificate, the host of the address by default.
+	ServerName string
+}
+
+// SyslogDialer returns a function connecting to a syslog receiver over udp, tcp or tls, for a SyslogWriter to
+// reconnect. The TLS files are loaded once.
+func SyslogDialer(network, addr string, tlsCfg TLSConfig) (func() (net.Conn, error), error) {
+	if network != "tls" {
+		return func() (net.Conn, error) { return net.Dial(network, addr) }, nil
+	}
+	cfg, err := tlsCfg.load()
+	if err != nil {
+		return nil, err
+	}
+	return func() (net.Conn, error) { return tls.Dial("tcp", addr, cfg) }, nil
+}
+
+func (c TLSConfig) load() (*tls.Config, error) {
+	cfg := &tls.Config{ServerName: c.ServerName, MinVersion: tls.VersionTLS12}
+	if c.CAFile != "" {
+		ca, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA: %w", err)
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificate found in %s", c.CAFile)
+		}
+	}
+	if c.CertFile != "" || c.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+// SyslogFormat is the protocol of the messages.
+type SyslogFormat string
+
+const (
+	// RFC5424Format is the IETF syslog protocol.
+	RFC5424Format SyslogFormat = "rfc5424"
+	// RFC3164Format is the legacy BSD syslog protocol, still the only one spoken by many appliances and relays.
+	RFC3164Format SyslogFormat = "rfc3164"
+)
+
+// ParseSyslogFormat validates the given format
+func ParseSyslogFormat(format string) (SyslogFormat, error) {
+	switch f := SyslogFormat(format); f {
+	case RFC5424Format, RFC3164Format:
+		return f, nil
+	}
+	return "", fmt.Errorf("%s is not a valid syslog format", format)
+}
+
 // SyslogLogger implements the Logger interface and handles logging to syslog
 type SyslogLogger struct {
-	conn     net.Conn
+	// conn is a net.Conn, or a SyslogWriter reconnecting on errors.
+	conn     io.Writer
 	facility syslog.Priority
 	hostname string
+	framing  SyslogFraming
+	format   SyslogFormat
+	sdID     string
+}
+
+// DefaultSDID is the SD-ID of the metadata, with the private enterprise number reserved for documentation.
+const DefaultSDID = "meta@32473"
+
+// SyslogOption configures a SyslogLogger
+type SyslogOption func(*SyslogLogger)
+
+// WithFraming sets the framing of the messages, required by receivers to split them over TCP.
+func WithFraming(framing SyslogFraming) SyslogOption {
+	return func(s *SyslogLogger) {
+		s.framing = framing
+	}
+}
+
+// WithFormat sets the protocol of the messages, RFC5424 by default.
+func WithFormat(format SyslogFormat) SyslogOption {
+	return func(s *SyslogLogger) {
+		s.format = format
+	}
+}
+
+// WithSDID sets the SD-ID of the element holding the metadata, validated by ParseSDID.
+func WithSDID(id string) SyslogOption {
+	return func(s *SyslogLogger) {
+		s.sdID = id
+	}
 }
 
 // NewSyslogLogger creates a new logger that writes to syslog
-func NewSyslogLogger(conn net.Conn, facility syslog.Priority) *SyslogLogger {
+func NewSyslogLogger(conn io.Writer, facility syslog.Priority, opts ...SyslogOption) *SyslogLogger {
 	hostname, err := os.Hostname()
 	if err != nil {
 		hostname = "unknown-host"
@@ -29,6 +156,11 @@ func NewSyslogLogger(conn net.Conn, facility syslog.Priority) *SyslogLogger {
 		conn:     conn,
 		hostname: hostname,
 		facility: facility,
+		format:   RFC5424Format,
+		sdID:     DefaultSDID,
+	}
+	for _, opt := range opts {
+		opt(logger)
 	}
 
 	return logger
@@ -46,10 +178,7 @@ func (s *SyslogLogger) HandleWithMetadata(labels model.LabelSet, timestamp time.
 		level = model.LabelValue("info")
 	}
 
-	serviceName, ok := labels["service_name"]
-	if !ok {
-		serviceName = model.LabelValue("unknown_service")
-	}
+	hostname, appName, pid := s.header(labels, metadata)
 
 	// Map log level to syslog severity
 	severityNum := getSeverityNumber(string(level))
@@ -57,69 +186,202 @@ func (s *SyslogLogger) HandleWithMetadata(labels model.LabelSet, timestamp time.
 	// Extract facility number (high 3 bits of priority)
 	facilityNum := int(s.facility) >> 3
 
-	var metadataStr string
-	if len(metadata) > 0 {
-		metadataStr = "[meta@1234"
-		for _, label := range metadata {
-			metadataStr += fmt.Sprintf(` %s="%s"`, label.Name, label.Value)
+	var msg string
+	if s.format == RFC3164Format {
+		msg = formatRFC3164Message(hostname, appName, pid, facilityNum, severityNum, timestamp, message, metadata)
+	} else {
+		var sd flog.StructuredData
+		if len(metadata) > 0 {
+			e := flog.SDElement{ID: s.sdID}
+			for _, label := range metadata {
+				e.Params = append(e.Params, flog.SDParam{Name: sdName(label.Name), Value: strings.ToValidUTF8(label.Value, "\uFFFD")})
+			}
+			sd = append(sd, e)
 		}
-		metadataStr += "]"
-	}
-
-	// Format message in RFC5424 format
-	rfc5424Msg := formatRFC5424Message(
-		s.hostname,
-		string(serviceName),
-		fmt.Sprintf("%d", os.Getpid()),
-		"-", // No message ID
-		facilityNum,
-		severityNum,
-		metadataStr,
-		message,
-	)
 
-	_, err := s.conn.Write([]byte(rfc5424Msg))
+		msg = formatRFC5424Message(
+			hostname,
+			appName,
+			strconv.Itoa(pid),
+			"-", // No message ID
+			facilityNum,
+			severityNum,
+			timestamp,
+			sd,
+			message,
+		)
+	}
+
+	// A single write per message, so that concurrent streams do not interleave frames.
+	_, err := s.conn.Write(frame(s.framing, msg))
 	return err
+}
 
+// header returns the HOSTNAME, APP-NAME and PROCID of a line, from the labels and metadata of its stream: the
+// host is the pod, and all the lines of a pod share a pid. The generator's own host and pid are the fallbacks.
+func (s *SyslogLogger) header(labels model.LabelSet, metadata push.LabelsAdapter) (hostname, appName string, pid int) {
+	lookup := func(name string) string {
+		for _, label := range metadata {
+			if label.Name == name {
+				return label.Value
+			}
+		}
+		return string(labels[model.LabelName(name)])
+	}
+
+	hostname = s.hostname
+	for _, name := range []string{"hostname", "host", "pod"} {
+		if value := lookup(name); value != "" {
+			hostname = value
+			break
+		}
+	}
+	appName = lookup("service_name")
+	if appName == "" {
+		appName = "unknown_service"
+	}
+	pid = os.Getpid()
+	if value, err := strconv.Atoi(lookup("pid")); err == nil && value > 0 {
+		pid = value
+	} else if pod := lookup("pod"); pod != "" {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(pod))
+		pid = 100 + int(h.Sum32()%32000)
+	}
+	return hostname, appName, pid
+}
+
+// frame delimits msg according to framing.
+func frame(framing SyslogFraming, msg string) []byte {
+	switch framing {
+	case OctetCountingFraming:
+		return []byte(strconv.Itoa(len(msg)) + " " + msg)
+	case NonTransparentFraming:
+		return []byte(strings.ReplaceAll(msg, "\n", "#012") + "\n")
+	default:
+		return []byte(msg)
+	}
+}
+
+// Close implements the Logger interface, flushing the messages buffered by a SyslogWriter. Lines written to a
+// net.Conn are unbuffered, closing the connection is all there is to do.
+func (s *SyslogLogger) Close(ctx context.Context) error {
+	switch conn := s.conn.(type) {
+	case *SyslogWriter:
+		return conn.Close(ctx)
+	case net.Conn:
+		if deadline, ok := ctx.Deadline(); ok {
+			_ = conn.SetWriteDeadline(deadline)
+		}
+		return conn.Close()
+	}
+	return nil
 }
 
 // formatRFC5424Message formats a message according to RFC5424 syslog protocol
 // Format: <PRI>VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
-func formatRFC5424Message(hostname, appName, procID, msgID string, facilityNum, severityNum int, metadata, message string) string {
+func formatRFC5424Message(hostname, appName, procID, msgID string, facilityNum, severityNum int, timestamp time.Time, sd flog.StructuredData, message string) string {
 	// Calculate priority value (facility * 8 + severity)
 	priority := facilityNum*8 + severityNum
 
-	// Create RFC5424 timestamp (YYYY-MM-DDTHH:MM:SS.SSSZ)
-	timestamp := time.Now().UTC().Format("2006-01-02T15:04:05.000Z")
+	// Format the RFC5424 message - strictly adhering to RFC5424 format, the header fields are PRINTUSASCII of a
+	// bounded length, or the NILVALUE "-" when empty.
+	// <PRI>VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
+	return fmt.Sprintf("<%d>1 %s %s %s %s %s %s %s",
+		priority,
+		timestamp.UTC().Format(rfc5424Timestamp),
+		printUSASCII(hostname, 255),
+		printUSASCII(appName, 48),
+		printUSASCII(procID, 128),
+		printUSASCII(msgID, 32),
+		sd,
+		message,
+	)
+}
 
-	// Replace empty values with NILVALUE as per RFC5424
-	if hostname == "" {
-		hostname = "-"
+// rfc5424Timestamp is the TIMESTAMP layout, RFC3339 with at most 6 digits of fractional seconds.
+const rfc5424Timestamp = "2006-01-02T15:04:05.000000Z07:00"
+
+// printUSASCII replaces the characters that are not printable US-ASCII, spaces included, of a header field and
+// truncates it to max characters.
+func printUSASCII(field string, max int) string {
+	if field == "" {
+		return "-"
 	}
-	if appName == "" {
-		appName = "-"
+	field = strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return '_'
+		}
+		return r
+	}, field)
+	if len(field) > max {
+		field = field[:max]
 	}
-	if procID == "" {
-		procID = "-"
+	return field
+}
+
+// sdName turns name into a valid SD-NAME: at most 32 printable US-ASCII characters, except '=', ' ', ']' and '"'.
+func sdName(name string) string {
+	name = printUSASCII(name, 32)
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '=', ']', '"':
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+// ParseSDID validates an enterprise SD-ID, name@<private enterprise number>. The names without an @ are reserved
+// to the IANA.
+func ParseSDID(id string) (string, error) {
+	name, number, ok := strings.Cut(id, "@")
+	if !ok || name == "" || number == "" || sdName(id) != id || strings.Contains(number, "@") {
+		return "", fmt.Errorf("%q is not a valid enterprise SD-ID, expected name@number", id)
 	}
-	if msgID == "" {
-		msgID = "-"
+	for _, r := range number {
+		if r != '.' && (r < '0' || r > '9') {
+			return "", fmt.Errorf("%q is not a valid enterprise SD-ID, expected name@number", id)
+		}
 	}
-	if metadata == "" {
-		metadata = "-"
+	return id, nil
+}
+
+// formatRFC3164Message formats a message according to the BSD syslog protocol, RFC3164. There is no structured
+// data, the metadata is appended to the message as key=value pairs.
+// Format: <PRI>TIMESTAMP HOSTNAME TAG[PID]: MSG
+func formatRFC3164Message(hostname, tag string, pid, facilityNum, severityNum int, timestamp time.Time, message string, metadata push.LabelsAdapter) string {
+	// The TAG is alphanumeric and at most 32 characters.
+	tag = strings.Map(func(r rune) rune {
+		if r > unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_' || r == '.') {
+			return '_'
+		}
+		return r
+	}, tag)
+	if len(tag) > 32 {
+		tag = tag[:32]
+	}
+	if hostname == "" {
+		hostname = "-"
 	}
 
-	// Format the RFC5424 message - strictly adhering to RFC5424 format
-	// <PRI>VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
-	return fmt.Sprintf("<%d>1 %s %s %s %s %s %s %s",
-		priority,
-		timestamp,
+	var sb strings.Builder
+	sb.WriteString(message)
+	for _, label := range metadata {
+		value := label.Value
+		if value == "" || strings.ContainsAny(value, " \t\"=") {
+			value = strconv.Quote(value)
+		}
+		sb.WriteString(" " + label.Name + "=" + value)
+	}
+
+	return fmt.Sprintf(flog.RFC3164Log,
+		facilityNum*8+severityNum,
+		timestamp.Format(time.Stamp),
 		hostname,
-		appName,
-		procID,
-		msgID,
-		metadata,
-		message,
+		tag,
+		pid,
+		sb.String(),
 	)
 }
 
@@ -1,16 +1,31 @@
 package log
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
 	"errors"
 	"fmt"
+	"io"
 	"log/syslog"
+	"math/big"
 	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/grafana/loki/pkg/push"
 	"github.com/prometheus/common/model"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestSyslogLoggerImplementsLoggerInterface(t *testing.T) {
@@ -89,6 +104,180 @@ func TestSyslogLoggerHandleWriteError(t *testing.T) {
 	assert.Equal(t, expectedErr, err)
 }
 
+func TestSyslogLoggerRFC5424(t *testing.T) {
+	mockConn := &MockConn{}
+	logger := NewSyslogLogger(mockConn, syslog.LOG_DAEMON, WithSDID("gen@53595"))
+
+	timestamp := time.Date(2024, time.March, 5, 7, 8, 9, 123456789, time.FixedZone("CET", 3600))
+	metadata := push.LabelsAdapter{
+		{Name: "pod", Value: "nginx-7d9f"},
+		{Name: "msg", Value: `say "hi" [ok] C:\tmp`},
+		{Name: "a name=with]spaces", Value: "x"},
+	}
+	err := logger.HandleWithMetadata(model.LabelSet{"level": "warn", "service_name": "nginx json"}, timestamp, "hello", metadata)
+	assert.NoError(t, err)
+
+	_, _, pid := logger.header(nil, metadata)
+	assert.Equal(t, fmt.Sprintf(`<28>1 2024-03-05T06:08:09.123456Z nginx-7d9f nginx_json %d - [gen@53595 pod="nginx-7d9f" msg="say \"hi\" [ok\] C:\\tmp" a_name_with_spaces="x"] hello`, pid), string(mockConn.LastWrite))
+	assert.NotEqual(t, os.Getpid(), pid, "the pid is the one of the pod")
+
+	for _, id := range []string{"meta", "meta@", "@1234", "a b@1234", "meta@12a", `me"ta@1234`} {
+		_, err := ParseSDID(id)
+		assert.Error(t, err, id)
+	}
+	_, err = ParseSDID("meta@32473.1")
+	assert.NoError(t, err)
+}
+
+func TestSyslogLoggerRFC3164(t *testing.T) {
+	mockConn := &MockConn{}
+	logger := NewSyslogLogger(mockConn, syslog.LOG_DAEMON, WithFormat(RFC3164Format))
+	logger.hostname = "host"
+
+	timestamp := time.Date(2024, time.March, 5, 7, 8, 9, 0, time.UTC)
+	metadata := push.LabelsAdapter{{Name: "trace_id", Value: "1234"}, {Name: "user", Value: "John Doe"}}
+	err := logger.HandleWithMetadata(model.LabelSet{"level": "error", "service_name": "test service"}, timestamp, "failed", metadata)
+
+	assert.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf(`<27>Mar  5 07:08:09 host test_service[%d]: failed trace_id=1234 user="John Doe"`, os.Getpid()), string(mockConn.LastWrite))
+
+	_, err = ParseSyslogFormat("rfc3339")
+	assert.Error(t, err)
+}
+
+func TestSyslogLoggerFraming(t *testing.T) {
+	labels := model.LabelSet{"level": "info", "service_name": "test-service"}
+
+	for _, tc := range []struct {
+		framing SyslogFraming
+		check   func(t *testing.T, frame string)
+	}{
+		{NoFraming, func(t *testing.T, frame string) {
+			assert.True(t, strings.HasPrefix(frame, "<"))
+			assert.True(t, strings.HasSuffix(frame, "hello"))
+		}},
+		{OctetCountingFraming, func(t *testing.T, frame string) {
+			length, msg, ok := strings.Cut(frame, " ")
+			assert.True(t, ok)
+			assert.Equal(t, strconv.Itoa(len(msg)), length)
+			assert.True(t, strings.HasPrefix(msg, "<"))
+		}},
+		{NonTransparentFraming, func(t *testing.T, frame string) {
+			assert.True(t, strings.HasPrefix(frame, "<"))
+			assert.True(t, strings.HasSuffix(frame, "hello\n"))
+		}},
+	} {
+		t.Run(string(tc.framing), func(t *testing.T) {
+			mockConn := &MockConn{}
+			logger := NewSyslogLogger(mockConn, syslog.LOG_DAEMON, WithFraming(tc.framing))
+			assert.NoError(t, logger.Handle(labels, time.Now(), "hello"))
+			assert.Equal(t, 1, mockConn.WriteCount)
+			tc.check(t, string(mockConn.LastWrite))
+		})
+	}
+
+	_, err := ParseSyslogFraming("netstring")
+	assert.Error(t, err)
+}
+
+func TestSyslogLoggerFramingMultiLine(t *testing.T) {
+	labels := model.LabelSet{"level": "error", "service_name": "test-service"}
+	message := "panic: oops\n\tat main.go:12\n\tat main.go:8"
+
+	mockConn := &MockConn{}
+	logger := NewSyslogLogger(mockConn, syslog.LOG_DAEMON, WithFraming(NonTransparentFraming))
+	require.NoError(t, logger.Handle(labels, time.Now(), message))
+	frame := string(mockConn.LastWrite)
+	assert.Equal(t, 1, strings.Count(frame, "\n"), "a multi-line message is a single frame")
+	assert.True(t, strings.HasSuffix(frame, "panic: oops#012\tat main.go:12#012\tat main.go:8\n"))
+
+	// Octet counting is transparent, the lines are kept.
+	logger = NewSyslogLogger(mockConn, syslog.LOG_DAEMON, WithFraming(OctetCountingFraming))
+	require.NoError(t, logger.Handle(labels, time.Now(), message))
+	assert.True(t, strings.HasSuffix(string(mockConn.LastWrite), message))
+}
+
+// selfSigned writes a self-signed certificate for 127.0.0.1, usable by servers and clients, to dir.
+func selfSigned(t *testing.T, dir string) (tls.Certificate, string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "syslog"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		DNSNames:              []string{"syslog.local"},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	require.NoError(t, err)
+	return cert, certFile, keyFile
+}
+
+func TestSyslogLoggerTLS(t *testing.T) {
+	cert, certFile, keyFile := selfSigned(t, t.TempDir())
+	pool := x509.NewCertPool()
+	pool.AddCert(cert.Leaf)
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+	})
+	require.NoError(t, err)
+	defer listener.Close()
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			received <- err.Error()
+			return
+		}
+		defer conn.Close()
+		data, _ := io.ReadAll(conn)
+		received <- string(data)
+
+		// Answer the handshake of the untrusting client.
+		if conn, err := listener.Accept(); err == nil {
+			_ = conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+
+	dial, err := SyslogDialer("tls", listener.Addr().String(), TLSConfig{
+		CAFile:     certFile,
+		CertFile:   certFile,
+		KeyFile:    keyFile,
+		ServerName: "syslog.local",
+	})
+	require.NoError(t, err)
+	logger := NewSyslogLogger(NewSyslogWriter(dial, DefaultSyslogWriterConfig()), syslog.LOG_DAEMON, WithFraming(OctetCountingFraming))
+	require.NoError(t, logger.Handle(model.LabelSet{"service_name": "test-service"}, time.Now(), "over tls"))
+	require.NoError(t, logger.Close(context.Background()))
+
+	frame := <-received
+	length, msg, _ := strings.Cut(frame, " ")
+	assert.Equal(t, strconv.Itoa(len(msg)), length)
+	assert.True(t, strings.HasSuffix(msg, "over tls"))
+
+	dial, err = SyslogDialer("tls", listener.Addr().String(), TLSConfig{})
+	require.NoError(t, err)
+	_, err = dial()
+	assert.Error(t, err, "the self-signed certificate is not trusted without the CA")
+}
+
 // MockConn is a simpler mock for testing
 type MockConn struct {
 	WriteFunc  func(p []byte) (n int, err error)
@@ -0,0 +1,245 @@
+package log
+
+import (
+	"context"
+	"log"
+	"net"
+	"sync"
+	"time"
+)
+
+// SyslogWriterConfig configures a SyslogWriter.
+type SyslogWriterConfig struct {
+	// BufferSize is the number of messages queued while the receiver is unreachable, the oldest are dropped beyond.
+	BufferSize int
+	// MinBackoff and MaxBackoff bound the jittered exponential backoff between dials.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// OnDrop is called with the number of messages dropped, and why.
+	OnDrop func(messages int, err error)
+	// OnWrite is called with the time every write to the connection took.
+	OnWrite func(d time.Duration)
+}
+
+// DefaultSyslogWriterConfig returns the buffering and backoff used when not configured otherwise.
+func DefaultSyslogWriterConfig() SyslogWriterConfig {
+	return SyslogWriterConfig{
+		BufferSize: 10000,
+		MinBackoff: 100 * time.Millisecond,
+		MaxBackoff: 10 * time.Second,
+	}
+}
+
+// bufferFullError is reported for the messages dropped because the receiver was unreachable for too long.
+type bufferFullError struct{}
+
+func (bufferFullError) Error() string  { return "syslog buffer full" }
+func (bufferFullError) Reason() string { return "buffer_full" }
+
+type syslogMessage struct {
+	data []byte
+}
+
+// SyslogWriter writes the messages of a SyslogLogger to a connection, dialed again whenever a write fails, so that
+// the generator survives restarts of the receiver. Messages are queued until written.
+type SyslogWriter struct {
+	cfg  SyslogWriterConfig
+	dial func() (net.Conn, error)
+
+	mu     sync.Mutex
+	queue  []*syslogMessage
+	conn   net.Conn
+	closed bool
+
+	wake     chan struct{}
+	quit     chan struct{}
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// NewSyslogWriter creates a writer connecting with dial. The first dial happens in the background, the receiver
+// does not have to be up yet.
+func NewSyslogWriter(dial func() (net.Conn, error), cfg SyslogWriterConfig) *SyslogWriter {
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = DefaultSyslogWriterConfig().BufferSize
+	}
+	w := &SyslogWriter{
+		cfg:  cfg,
+		dial: dial,
+		wake: make(chan struct{}, 1),
+		quit: make(chan struct{}),
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// Write queues a message, dropping the oldest one when the buffer is full. It only fails once closed.
+func (w *SyslogWriter) Write(p []byte) (int, error) {
+	msg := &syslogMessage{data: make([]byte, len(p))}
+	copy(msg.data, p)
+
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return 0, ErrLoggerClosed
+	}
+	dropped := 0
+	if len(w.queue) >= w.cfg.BufferSize {
+		dropped = len(w.queue) - w.cfg.BufferSize + 1
+		w.queue = w.queue[dropped:]
+	}
+	w.queue = append(w.queue, msg)
+	w.mu.Unlock()
+
+	if dropped > 0 {
+		w.drop(dropped, bufferFullError{})
+	}
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+	return len(p), nil
+}
+
+func (w *SyslogWriter) drop(messages int, err error) {
+	if w.cfg.OnDrop != nil {
+		w.cfg.OnDrop(messages, err)
+	}
+}
+
+// Close writes the queued messages, dropping the ones left once ctx is done, and closes the connection. It can be
+// called again, with another deadline.
+func (w *SyslogWriter) Close(ctx context.Context) error {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.quit)
+	}
+	w.mu.Unlock()
+
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	// Unblock the pending dial backoff or write.
+	w.stopOnce.Do(func() { close(w.stop) })
+	w.mu.Lock()
+	if w.conn != nil {
+		w.conn.Close()
+	}
+	w.mu.Unlock()
+	<-w.done
+
+	w.mu.Lock()
+	left := len(w.queue)
+	w.queue = nil
+	w.mu.Unlock()
+	if left > 0 {
+		w.drop(left, ctx.Err())
+	}
+	return ctx.Err()
+}
+
+func (w *SyslogWriter) run() {
+	defer close(w.done)
+	defer func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		if w.conn != nil {
+			w.conn.Close()
+			w.conn = nil
+		}
+	}()
+
+	var conn net.Conn
+	attempt := 0
+	for {
+		msg, ok := w.next()
+		if !ok {
+			return
+		}
+
+		if conn == nil {
+			c, err := w.dial()
+			if err != nil {
+				if attempt == 0 {
+					log.Printf("Failed to connect to syslog, retrying: %v", err)
+				}
+				select {
+				case <-w.stop:
+					return
+				case <-time.After(backoff(w.cfg.MinBackoff, w.cfg.MaxBackoff, attempt)):
+				}
+				attempt++
+				continue
+			}
+			if attempt > 0 {
+				log.Printf("Reconnected to syslog after %d attempts", attempt)
+			}
+			conn, attempt = c, 0
+			w.mu.Lock()
+			w.conn = conn
+			w.mu.Unlock()
+		}
+
+		start := time.Now()
+		_, err := conn.Write(msg.data)
+		if w.cfg.OnWrite != nil {
+			w.cfg.OnWrite(time.Since(start))
+		}
+		if err != nil {
+			// The message is written again once reconnected.
+			log.Printf("Lost the syslog connection: %v", err)
+			conn.Close()
+			conn = nil
+			w.mu.Lock()
+			w.conn = nil
+			w.mu.Unlock()
+			select {
+			case <-w.stop:
+				return
+			default:
+			}
+			continue
+		}
+
+		w.mu.Lock()
+		// Unless it was dropped in the meantime.
+		if len(w.queue) > 0 && w.queue[0] == msg {
+			w.queue = w.queue[1:]
+		}
+		w.mu.Unlock()
+	}
+}
+
+// next returns the oldest queued message, waiting for one. It returns false once closed with an empty queue, or
+// stopped.
+func (w *SyslogWriter) next() (*syslogMessage, bool) {
+	quit := w.quit
+	for {
+		w.mu.Lock()
+		if len(w.queue) > 0 {
+			msg := w.queue[0]
+			w.mu.Unlock()
+			return msg, true
+		}
+		w.mu.Unlock()
+		if quit == nil {
+			return nil, false
+		}
+
+		select {
+		case <-w.wake:
+		case <-quit:
+			// Writes are rejected from now on, the queue only needs to be checked once more.
+			quit = nil
+		case <-w.stop:
+			return nil, false
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package log
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type drops struct {
+	mu      sync.Mutex
+	reasons map[string]int
+}
+
+func (d *drops) add(messages int, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.reasons == nil {
+		d.reasons = map[string]int{}
+	}
+	d.reasons[ErrorReason(err)] += messages
+}
+
+func (d *drops) get() map[string]int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.reasons
+}
+
+func testSyslogWriterConfig(d *drops) SyslogWriterConfig {
+	return SyslogWriterConfig{
+		BufferSize: 2,
+		MinBackoff: time.Millisecond,
+		MaxBackoff: 5 * time.Millisecond,
+		OnDrop:     d.add,
+	}
+}
+
+func TestSyslogWriterReconnects(t *testing.T) {
+	// Reserve an address the receiver is not listening on yet.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+	require.NoError(t, l.Close())
+
+	var d drops
+	w := NewSyslogWriter(func() (net.Conn, error) { return net.Dial("tcp", addr) }, testSyslogWriterConfig(&d))
+	for _, msg := range []string{"a\n", "b\n", "c\n"} {
+		_, err := w.Write([]byte(msg))
+		require.NoError(t, err)
+	}
+	assert.Equal(t, map[string]int{"buffer_full": 1}, d.get(), "the oldest message is dropped")
+
+	l, err = net.Listen("tcp", addr)
+	require.NoError(t, err)
+	defer l.Close()
+	conn, err := l.Accept()
+	require.NoError(t, err)
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	for _, want := range []string{"b\n", "c\n"} {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		assert.Equal(t, want, line)
+	}
+	require.NoError(t, w.Close(context.Background()))
+	_, err = w.Write([]byte("d\n"))
+	assert.ErrorIs(t, err, ErrLoggerClosed)
+}
+
+func TestSyslogWriterCloseUnreachable(t *testing.T) {
+	var d drops
+	w := NewSyslogWriter(func() (net.Conn, error) { return nil, &net.OpError{Op: "dial", Err: net.UnknownNetworkError("down")} }, testSyslogWriterConfig(&d))
+	_, err := w.Write([]byte("a\n"))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, w.Close(ctx), context.DeadlineExceeded)
+	assert.Equal(t, map[string]int{"timeout": 1}, d.get(), "the messages left are dropped")
+
+	expired, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.NotPanics(t, func() { _ = w.Close(expired) }, "closing twice")
+	assert.Equal(t, map[string]int{"timeout": 1}, d.get())
+}
@@ -4,7 +4,6 @@ import (
 	"github.com/brianvoe/gofakeit/v7"
 	"github.com/grafana/loki/pkg/push"
 	"github.com/prometheus/common/model"
-	"math/rand"
 	"strconv"
 	"strings"
 	"time"
@@ -72,7 +71,8 @@ const (
 	DEBUG = model.LabelValue("debug")
 )
 
-var level = []model.LabelValue{
+// Levels are all the levels the generator emits, from least to most severe.
+var Levels = []model.LabelValue{
 	DEBUG,
 	INFO,
 	WARN,
@@ -82,30 +82,70 @@ var level = []model.LabelValue{
 var OrgIDs = []string{"1218", "29", "1010", "2419", "2919"}
 var UserIDs = []string{"14234", "03428", "10572", "94223", "08203", "93820", "12345", "54321", "67890"}
 
-var defaultTraceId = gofakeit.UUID()
-
 var lessRandomPodLabelName = "tempo-ingester"
 
-func RandLevel() model.LabelValue {
-	r := rand.Intn(100)
-	if r < 5 {
-		return ERROR
-	} else if r < 10 {
-		return WARN
-	} else {
-		return level[rand.Intn(len(level)-2)]
+// LevelWeight is the relative weight of a level within a LevelMix.
+type LevelWeight struct {
+	Level  model.LabelValue
+	Weight int
+}
+
+// LevelMix is a weighted distribution of log levels.
+type LevelMix []LevelWeight
+
+// DefaultLevelMix is the distribution used by RandLevel: 5% errors, 5% warnings, the rest split between debug and info.
+var DefaultLevelMix = LevelMix{
+	{Level: DEBUG, Weight: 45},
+	{Level: INFO, Weight: 45},
+	{Level: WARN, Weight: 5},
+	{Level: ERROR, Weight: 5},
+}
+
+// Rand picks a level according to the weights of the mix.
+func (m LevelMix) Rand(f *gofakeit.Faker) model.LabelValue {
+	total := 0
+	for _, l := range m {
+		total += l.Weight
+	}
+	if total <= 0 {
+		return INFO
+	}
+	r := f.IntN(total)
+	for _, l := range m {
+		r -= l.Weight
+		if r < 0 {
+			return l.Level
+		}
 	}
+	return INFO
 }
 
-func RandURI() string {
-	return URI[rand.Intn(len(URI))]
+// Seed regenerates the value pools shared by all streams from f, so that a seeded run draws the same values.
+func Seed(f *gofakeit.Faker) {
+	filesNames = randFileNames(f)
 }
 
-func ForAllClusters(namespace, svc model.LabelValue, cb func(model.LabelSet, push.LabelsAdapter)) {
-	podCount := rand.Intn(10) + 1
-	if string(svc) == lessRandomPodLabelName {
-		podCount = 8
+// Fork derives an independent faker from f. Every goroutine producing logs owns its own faker,
+// so the values of a seeded run don't depend on goroutine scheduling.
+func Fork(f *gofakeit.Faker) *gofakeit.Faker {
+	return gofakeit.New(f.Uint64())
+}
+
+func RandLevel(f *gofakeit.Faker) model.LabelValue {
+	return DefaultLevelMix.Rand(f)
+}
+
+func RandURI(f *gofakeit.Faker) string {
+	return URI[f.IntN(len(URI))]
+}
+
+// ForAllClusters calls cb for each of the podCount pods of svc in every cluster.
+// A podCount of zero or less picks a random count between 1 and 10.
+func ForAllClusters(f *gofakeit.Faker, namespace, svc model.LabelValue, podCount int, cb func(model.LabelSet, push.LabelsAdapter)) {
+	if podCount <= 0 {
+		podCount = f.IntN(10) + 1
 	}
+	traceID := ""
 	for _, cluster := range Clusters {
 		for i := 0; i < podCount; i++ {
 			clusterInt := 0
@@ -113,82 +153,89 @@ func ForAllClusters(namespace, svc model.LabelValue, cb func(model.LabelSet, pus
 				clusterInt += int(char)
 			}
 
+			metadata := RandStructuredMetadata(f, string(svc), i, traceID)
+			traceID = metadata[0].Value
+
 			cb(model.LabelSet{
-				"env":              model.LabelValue(namespaces[rand.Intn(len(namespaces))]),
+				"env":              model.LabelValue(namespaces[f.IntN(len(namespaces))]),
 				"cluster":          model.LabelValue(cluster),
 				"__stream_shard__": model.LabelValue(shards[clusterInt%len(shards)]),
 				"namespace":        namespace,
 				"service_name":     svc,
 				"file":             "C:\\Grafana\\logs\\" + namespace + ".txt",
-			}, RandStructuredMetadata(string(svc), i))
+			}, metadata)
 		}
 	}
 }
 
-func RandSeq(n int) string {
+func RandSeq(f *gofakeit.Faker, n int) string {
 	letters := []rune("abcdefghijklmnopqrstuvwxyz0123456789")
 	b := make([]rune, n)
 	for i := range b {
-		b[i] = letters[rand.Intn(len(letters))]
+		b[i] = letters[f.IntN(len(letters))]
 	}
 	return string(b)
 }
 
-func RandOrgID() string {
-	return OrgIDs[rand.Intn(len(OrgIDs))]
+func RandOrgID(f *gofakeit.Faker) string {
+	return OrgIDs[f.IntN(len(OrgIDs))]
 }
 
-func RandUserID() string {
-	return UserIDs[rand.Intn(len(UserIDs))]
+func RandUserID(f *gofakeit.Faker) string {
+	return UserIDs[f.IntN(len(UserIDs))]
 }
 
-func RandError() string {
-	switch rand.Intn(10) {
+func RandError(f *gofakeit.Faker) string {
+	switch f.IntN(10) {
 	case 0:
-		return gofakeit.ErrorDatabase().Error()
+		return f.ErrorDatabase().Error()
 	case 1:
-		return gofakeit.ErrorGRPC().Error()
+		return f.ErrorGRPC().Error()
 	case 2:
-		return gofakeit.ErrorObject().Error()
+		return f.ErrorObject().Error()
 	case 3:
-		return gofakeit.ErrorRuntime().Error()
+		return f.ErrorRuntime().Error()
 	case 4:
-		return gofakeit.ErrorHTTP().Error()
+		return f.ErrorHTTP().Error()
 	default:
-		return gofakeit.Error().Error()
+		return f.Error().Error()
 	}
 }
 
-var filesNames = []string{gofakeit.ProductName(), gofakeit.ProductName(), gofakeit.ProductName(), gofakeit.Word(), gofakeit.Word()}
+var filesNames = randFileNames(gofakeit.GlobalFaker)
+
+func randFileNames(f *gofakeit.Faker) []string {
+	return []string{f.ProductName(), f.ProductName(), f.ProductName(), f.Word(), f.Word()}
+}
 
-func RandFileName() string {
-	return strings.ReplaceAll(strings.ToLower(filesNames[rand.Intn(len(filesNames))]), " ", "_")
+func RandFileName(f *gofakeit.Faker) string {
+	return strings.ReplaceAll(strings.ToLower(filesNames[f.IntN(len(filesNames))]), " ", "_")
 }
 
-func RandDuration() string {
-	return (time.Duration(gofakeit.Number(1, 30000)) * time.Millisecond).String()
+func RandDuration(f *gofakeit.Faker) string {
+	return (time.Duration(f.Number(1, 30000)) * time.Millisecond).String()
 }
 
-func RandTraceID(prevTrace string) string {
+func RandTraceID(f *gofakeit.Faker, prevTrace string) string {
 	// 50% chance to use `prevTrace` if it is set
-	if prevTrace != "" && rand.Intn(2) == 0 {
+	if prevTrace != "" && f.IntN(2) == 0 {
 		return prevTrace
 	}
 
-	newTrace := gofakeit.UUID()
-	defaultTraceId = newTrace
-	return newTrace
+	return f.UUID()
 }
 
-func RandStructuredMetadata(svc string, index int) push.LabelsAdapter {
-	podName := svc + "-" + RandSeq(5)
+// RandStructuredMetadata returns the traceID, pod and user metadata of the index-th pod of svc.
+// The traceID has a 50% chance to be prevTrace, when set.
+func RandStructuredMetadata(f *gofakeit.Faker, svc string, index int, prevTrace string) push.LabelsAdapter {
+	podName := svc + "-" + RandSeq(f, 5)
 	if svc == lessRandomPodLabelName {
 		// Hardcode the pod name ID for the tempo-ingester service so we can consistently query metadata in e2e tests.
-		podName = lessRandomPodLabelName + "-hc-" + strconv.Itoa(index) + RandSeq(3)
+		podName = lessRandomPodLabelName + "-hc-" + strconv.Itoa(index) + RandSeq(f, 3)
 	}
 	return push.LabelsAdapter{
-		push.LabelAdapter{Name: "traceID", Value: RandTraceID(defaultTraceId)},
+		push.LabelAdapter{Name: "traceID", Value: RandTraceID(f, prevTrace)},
 		push.LabelAdapter{Name: "pod", Value: podName},
-		push.LabelAdapter{Name: "user", Value: RandUserID()},
+		push.LabelAdapter{Name: "user", Value: RandUserID(f)},
 	}
 }
@@ -0,0 +1,39 @@
+package log
+
+import (
+	"testing"
+
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/grafana/loki/pkg/push"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestForAllClustersSeeded(t *testing.T) {
+	streams := func(seed uint64) ([]model.LabelSet, []push.LabelsAdapter) {
+		var labels []model.LabelSet
+		var metadata []push.LabelsAdapter
+		ForAllClusters(gofakeit.New(seed), "tempo-prod", "tempo-distributor", 0, func(l model.LabelSet, m push.LabelsAdapter) {
+			labels = append(labels, l)
+			metadata = append(metadata, m)
+		})
+		return labels, metadata
+	}
+
+	labels, metadata := streams(42)
+	sameLabels, sameMetadata := streams(42)
+	assert.Equal(t, labels, sameLabels)
+	assert.Equal(t, metadata, sameMetadata)
+
+	_, otherMetadata := streams(43)
+	assert.NotEqual(t, metadata, otherMetadata)
+}
+
+func TestLevelMixRand(t *testing.T) {
+	f := gofakeit.New(1)
+	mix := LevelMix{{Level: WARN, Weight: 0}, {Level: ERROR, Weight: 1}}
+	for i := 0; i < 100; i++ {
+		assert.Equal(t, ERROR, mix.Rand(f))
+	}
+	assert.Equal(t, INFO, LevelMix{}.Rand(f))
+}
@@ -2,113 +2,410 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log/syslog"
-	"net"
+	"net/http"
 	"os"
 	"os/signal"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/grafana/explore-logs/generator/flog"
 	"github.com/grafana/explore-logs/generator/log"
-	"github.com/grafana/loki-client-go/loki"
 	"github.com/grafana/loki/pkg/push"
-	"github.com/prometheus/common/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/prometheus/common/model"
 )
 
 func main() {
 	url := flag.String("url", "http://localhost:3100/loki/api/v1/push", "Loki URL")
 	dry := flag.Bool("dry", false, "Dry run: log to stdout instead of Loki")
-	useOtel := flag.Bool("otel", true, "Ship logs for otel apps to OTel collector")
+	useOtel := flag.Bool("otel", true, "Ship logs for otel apps over OTLP")
+	otlpProtocol := flag.String("otlp-protocol", log.OTLPGRPC, "OTLP protocol of the otel apps: 'grpc' to a collector, or 'http/protobuf' and 'http/json' straight to Loki")
+	otlpEndpoint := flag.String("otlp-endpoint", "", "OTLP endpoint: host:port of the gRPC collector (default: $OTEL_EXPORTER_OTLP_ENDPOINT or localhost:4317), or OTLP/HTTP logs URL (default: the /otlp/v1/logs of the tenant's Loki)")
 	tenantId := flag.String("tenant-id", "", "Loki tenant ID")
 	token := flag.String("token", "", "GEL token")
+	pushEncoding := flag.String("push-encoding", log.ProtobufEncoding, "Loki push payload: 'protobuf' (snappy compressed) or 'json'")
+	pushGzip := flag.Bool("push-gzip", false, "Gzip JSON push payloads")
+	batchSize := flag.Int("batch-size", 1<<20, "Size in bytes of the lines at which a batch is pushed to Loki")
+	batchWait := flag.Duration("batch-wait", time.Second, "Longest time a line waits for its batch to be pushed to Loki")
+	maxRetries := flag.Int("max-retries", 5, "Retries of a batch, or of an OTLP/HTTP export, failing with a 429, a 5xx or a network error")
+	minBackoff := flag.Duration("min-backoff", 100*time.Millisecond, "Initial backoff between retries, 429 responses wait for their Retry-After instead")
+	maxBackoff := flag.Duration("max-backoff", 10*time.Second, "Maximum backoff between retries")
+	pushTimeout := flag.Duration("push-timeout", 10*time.Second, "Timeout of a single push request")
+	tenantsFile := flag.String("tenants", "", "YAML or JSON file listing the tenants to push to, each with its own URL, credentials, scenario and volume (default: the single -tenant-id tenant)")
 
-	useSyslog := flag.Bool("syslog", false, "Output RFC5424 formatted logs to syslog instead of stdout")
-	syslogProtocol := flag.String("syslog-network", "udp", "Syslog network type: 'udp' or 'tcp'")
+	useSyslog := flag.Bool("syslog", false, "Output logs to syslog instead of stdout")
+	syslogProtocol := flag.String("syslog-network", "udp", "Syslog network type: 'udp', 'tcp' or 'tls' (RFC5425)")
 	syslogAddr := flag.String("syslog-addr", "127.0.0.1:514", "Syslog remote address (e.g., '127.0.0.1:514')")
+	syslogFormat := flag.String("syslog-format", string(log.RFC5424Format), "Syslog message format: 'rfc5424' or 'rfc3164' (BSD)")
+	syslogSDID := flag.String("syslog-sd-id", log.DefaultSDID, "RFC5424 SD-ID of the structured data holding the metadata, name@<private enterprise number>")
+	syslogTLSCA := flag.String("syslog-tls-ca", "", "CA file verifying the syslog server certificate with -syslog-network tls (default: system roots)")
+	syslogTLSCert := flag.String("syslog-tls-cert", "", "Client certificate file for syslog collectors requiring mutual TLS")
+	syslogTLSKey := flag.String("syslog-tls-key", "", "Client key file for syslog collectors requiring mutual TLS")
+	syslogTLSServerName := flag.String("syslog-tls-server-name", "", "Name verified in the syslog server certificate (default: the host of -syslog-addr)")
+	syslogBuffer := flag.Int("syslog-buffer", log.DefaultSyslogWriterConfig().BufferSize, "Messages buffered while the syslog receiver is unreachable, the oldest are dropped beyond. The connection is retried with -min-backoff and -max-backoff")
+	syslogFraming := flag.String("syslog-framing", string(log.OctetCountingFraming), "Framing of the messages over TCP: 'octet-counting' or 'non-transparent' (LF terminated, the LFs of multi-line messages escaped as #012), UDP sends one message per datagram")
+
+	fileDir := flag.String("file-dir", "", "Write the lines of every stream to <dir>/<namespace>/<service>/<labels>.log instead of Loki")
+	fileType := flag.String("file-type", "log", "File output type: 'log' or 'gz' to gzip the rotated segments")
+	fileSplitBy := flag.Int("file-split-by", 0, "Rotate the files every n lines, or bytes with -file-split-unit bytes (default: never)")
+	fileSplitUnit := flag.String("file-split-unit", "lines", "Unit of -file-split-by: 'lines' or 'bytes'")
+	fileOverwrite := flag.Bool("file-overwrite", false, "Truncate the files left by a previous run instead of appending to them")
+	podsDir := flag.String("pods-dir", "", "Write the lines of every stream to <dir>/<namespace>_<pod>_<uid>/<service>/0.log like a Kubernetes node's /var/log/pods instead of Loki")
+	podsFormat := flag.String("pods-format", "cri", "Container runtime format of the -pods-dir lines: 'cri' or 'docker' (json-file)")
+	podsMaxLineSize := flag.Int("pods-max-line-size", log.DefaultMaxLineSize, "Split the -pods-dir lines longer than this many bytes into partial lines")
+	podsContainersDir := flag.String("pods-containers-dir", "", "Also add the <pod>_<namespace>_<service>-<id>.log symlinks of /var/log/containers to this directory")
+
+	configFile := flag.String("config", "", "YAML or JSON scenario file describing the generated namespaces and services (default: built-in scenario)")
+	incidentsFile := flag.String("incidents", "", "YAML or JSON file with incidents to apply on top of the scenario, e.g. scenarios/incidents-example.yaml")
+	seed := flag.Uint64("seed", 0, "Seed for reproducible streams, pod names, trace IDs and lines (default: random seed)")
+	rateMultiplier := flag.Float64("rate-multiplier", 1, "Multiplies the lines per second of every service, e.g. to scale the whole fleet up for load tests")
+	metricsAddr := flag.String("metrics-addr", "", "Address serving the generator's Prometheus metrics on /metrics, e.g. :9095 (default: disabled)")
+	shutdownTimeout := flag.Duration("shutdown-timeout", 10*time.Second, "Deadline to stop the generators and flush the pending logs of every sink on SIGINT or SIGTERM")
+
+	from := flag.String("from", "", "Backfill logs from this RFC3339 time or duration ago (e.g. '24h', '7d') before generating live logs")
+	to := flag.String("to", "", "End of the backfill as an RFC3339 time or duration ago (default: now)")
+	live := flag.Bool("live", true, "Keep generating live logs once the backfill is done, exit otherwise")
+	backfillWindow := flag.Duration("backfill-window", time.Minute, "Virtual time span of the lines buffered and sorted by timestamp before being pushed during the backfill")
 
 	flag.Parse()
 
-	cfg, err := loki.NewDefaultConfig(*url)
-	if err != nil {
-		panic(err)
+	if *seed == 0 {
+		*seed = gofakeit.New(0).Uint64()
 	}
-	cfg.BackoffConfig.MaxRetries = 1
-	cfg.BackoffConfig.MinBackoff = 100 * time.Millisecond
-	cfg.BackoffConfig.MaxBackoff = 100 * time.Millisecond
+	fmt.Fprintf(os.Stderr, "Generating logs with -seed=%d\n", *seed)
+	f := gofakeit.New(*seed)
+	flog.Seed(f)
+	log.Seed(f)
 
-	if *tenantId != "" {
-		cfg.TenantID = *tenantId
+	if *rateMultiplier <= 0 {
+		panic("-rate-multiplier must be positive")
 	}
 
-	if *token != "" {
-		t := config.Secret(*token)
-		cfg.Client.BasicAuth = &config.BasicAuth{
-			Username: *tenantId,
-			Password: t,
+	tenants := []TenantConfig{{ID: *tenantId, Password: *token}}
+	if *tenantsFile != "" {
+		var err error
+		if tenants, err = LoadTenants(*tenantsFile); err != nil {
+			panic(err)
 		}
 	}
 
-	client, err := loki.New(cfg)
-	if err != nil {
-		panic(err)
-	}
-	defer client.Stop()
+	metrics := log.NewMetrics(prometheus.DefaultRegisterer)
 
-	var logger log.Logger = client
+	// sinks are closed on shutdown, flushing their pending lines.
+	var sinks []log.Closer
+	// sharedLogger replaces the Loki clients of all tenants.
+	var sharedLogger log.Logger
+	sink := "loki"
 
 	// Configure the output based on flags, dry trumps all
 	if *dry {
 		// Use stdout for output
-		logger = log.LoggerFunc(func(labels model.LabelSet, timestamp time.Time, message string, metadata push.LabelsAdapter) error {
+		sink = "stdout"
+		sharedLogger = log.LoggerFunc(func(labels model.LabelSet, timestamp time.Time, message string, metadata push.LabelsAdapter) error {
 			fmt.Println(labels, timestamp, message, metadata)
 			return nil
 		})
 	} else if *useSyslog {
-		conn, err := net.Dial(*syslogProtocol, *syslogAddr)
+		format, err := log.ParseSyslogFormat(*syslogFormat)
+		if err != nil {
+			panic(err)
+		}
+		sdID, err := log.ParseSDID(*syslogSDID)
+		if err != nil {
+			panic(err)
+		}
+		framing, err := log.ParseSyslogFraming(*syslogFraming)
 		if err != nil {
 			panic(err)
 		}
-		defer conn.Close()
-		logger = log.NewSyslogLogger(conn, syslog.LOG_INFO|syslog.LOG_DAEMON)
+		if strings.HasPrefix(*syslogProtocol, "udp") {
+			framing = log.NoFraming
+		}
+		if *syslogProtocol == "tls" && framing != log.OctetCountingFraming {
+			panic("syslog over TLS requires octet-counting framing")
+		}
+		dial, err := log.SyslogDialer(*syslogProtocol, *syslogAddr, log.TLSConfig{
+			CAFile:     *syslogTLSCA,
+			CertFile:   *syslogTLSCert,
+			KeyFile:    *syslogTLSKey,
+			ServerName: *syslogTLSServerName,
+		})
+		if err != nil {
+			panic(err)
+		}
+		writer := log.NewSyslogWriter(dial, log.SyslogWriterConfig{
+			BufferSize: *syslogBuffer,
+			MinBackoff: *minBackoff,
+			MaxBackoff: *maxBackoff,
+			OnDrop: func(messages int, err error) {
+				metrics.PushFailed("syslog", messages, err)
+			},
+			OnWrite: func(d time.Duration) {
+				metrics.ObservePush("syslog", d)
+			},
+		})
+		sharedLogger = log.NewSyslogLogger(writer, syslog.LOG_INFO|syslog.LOG_DAEMON, log.WithFraming(framing), log.WithFormat(format), log.WithSDID(sdID))
+		sinks = append(sinks, sharedLogger)
+		sink = "syslog"
+	} else if *fileDir != "" {
+		if *fileType != "log" && *fileType != "gz" {
+			panic(fmt.Sprintf("%s is not a valid file type", *fileType))
+		}
+		if *fileSplitUnit != "lines" && *fileSplitUnit != "bytes" {
+			panic(fmt.Sprintf("%s is not a valid split unit", *fileSplitUnit))
+		}
+		sharedLogger = log.NewFileLogger(*fileDir, flog.RotateOptions{
+			Gzip:       *fileType == "gz",
+			SplitBy:    *fileSplitBy,
+			SplitBytes: *fileSplitUnit == "bytes",
+			Overwrite:  *fileOverwrite,
+		})
+		sinks = append(sinks, sharedLogger)
+		sink = "file"
+	} else if *podsDir != "" {
+		format, err := log.ParseContainerLogFormat(*podsFormat)
+		if err != nil {
+			panic(err)
+		}
+		sharedLogger = log.NewPodsLogger(log.PodsConfig{
+			Dir:           *podsDir,
+			Format:        format,
+			MaxLineSize:   *podsMaxLineSize,
+			ContainersDir: *podsContainersDir,
+		})
+		sinks = append(sinks, sharedLogger)
+		sink = "pods"
 	}
 
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	lokiConfig := log.DefaultLokiConfig(*url)
+	lokiConfig.Encoding = *pushEncoding
+	lokiConfig.Gzip = *pushGzip
+	lokiConfig.BatchSize = *batchSize
+	lokiConfig.BatchWait = *batchWait
+	lokiConfig.MinBackoff = *minBackoff
+	lokiConfig.MaxBackoff = *maxBackoff
+	lokiConfig.MaxRetries = *maxRetries
+	lokiConfig.Timeout = *pushTimeout
+	lokiConfig.OnStreamError = func(_ string, entries int, err error) {
+		metrics.PushFailed("loki", entries, err)
+	}
+	lokiConfig.OnPush = func(d time.Duration) {
+		metrics.ObservePush("loki", d)
+	}
+	if *metricsAddr != "" {
+		go func() {
+			http.Handle("/metrics", promhttp.Handler())
+			if err := http.ListenAndServe(*metricsAddr, nil); err != nil {
+				fmt.Fprintf(os.Stderr, "Metrics server: %s\n", err)
+			}
+		}()
+	}
+
+	// docker stop sends SIGTERM.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
-	// Creates and starts all apps.
-	for namespace, apps := range generators {
-		for serviceName, generator := range apps {
-			log.ForAllClusters(
-				namespace,
-				serviceName,
-				func(labels model.LabelSet, metadata push.LabelsAdapter) {
-					// Remove `metadata` from nginx logs
-					if serviceName == "nginx" {
-						metadata = push.LabelsAdapter{}
-					}
-					if strings.Contains(string(serviceName), "-otel") {
-						if !*useOtel {
+	var running sync.WaitGroup
+
+	clocks := ClockSource(realClocks)
+	var backfill *Backfill
+	// start is the beginning of the generation, incidents are scheduled from it.
+	start := time.Now()
+	if *from != "" {
+		var err error
+		now := start
+		start, err = parseTime(*from, now)
+		if err != nil {
+			panic(err)
+		}
+		end := now
+		if *to != "" {
+			if end, err = parseTime(*to, now); err != nil {
+				panic(err)
+			}
+		}
+		if !start.Before(end) {
+			panic(fmt.Sprintf("-from %s must be before -to %s", start.Format(time.RFC3339), end.Format(time.RFC3339)))
+		}
+		fmt.Fprintf(os.Stderr, "Backfilling logs from %s to %s\n", start.Format(time.RFC3339), end.Format(time.RFC3339))
+		backfill = NewBackfill(ctx, start, end, *backfillWindow)
+		clocks = backfill.Clocks
+	}
+
+	// The otel apps of all tenants share their exporters.
+	otelPool := log.NewOtelPool(log.OTLPHooks{
+		OnExport: func(d time.Duration) {
+			metrics.ObservePush("otel", d)
+		},
+		OnExportError: func(records int, err error) {
+			metrics.PushFailed("otel", records, err)
+		},
+	})
+	sinks = append(sinks, otelPool)
+
+	// Creates and starts all apps of every tenant.
+	for _, tenant := range tenants {
+		if tenant.URL == "" {
+			tenant.URL = *url
+		}
+		if tenant.Scenario == "" {
+			tenant.Scenario = *configFile
+		}
+		if tenant.Incidents == "" {
+			tenant.Incidents = *incidentsFile
+		}
+		multiplier := *rateMultiplier
+		if tenant.RateMultiplier > 0 {
+			multiplier *= tenant.RateMultiplier
+		}
+
+		scenario, err := tenant.LoadScenario()
+		if err != nil {
+			panic(err)
+		}
+		incidents, err := NewIncidents(scenario.Incidents, start)
+		if err != nil {
+			panic(err)
+		}
+
+		logger := sharedLogger
+		if logger == nil {
+			client, err := tenant.NewLokiLogger(lokiConfig)
+			if err != nil {
+				panic(fmt.Sprintf("tenant %s: %s", tenant.ID, err))
+			}
+			logger = client
+			sinks = append(sinks, logger)
+		}
+		logger = metrics.Instrument(sink, logger)
+		if backfill != nil {
+			logger = backfill.Logger(logger)
+		}
+		otlpConfig := tenant.OTLPConfig(log.OTLPConfig{
+			Protocol:   *otlpProtocol,
+			Endpoint:   *otlpEndpoint,
+			Timeout:    *pushTimeout,
+			MinBackoff: *minBackoff,
+			MaxBackoff: *maxBackoff,
+			MaxRetries: *maxRetries,
+		})
+
+		for _, namespace := range scenario.Namespaces {
+			for _, svc := range namespace.Services {
+				generator := formats[svc.Format](svc)
+				var streams []*Stream
+				log.ForAllClusters(
+					f,
+					model.LabelValue(namespace.Name),
+					model.LabelValue(svc.Name),
+					svc.Pods,
+					func(labels model.LabelSet, metadata push.LabelsAdapter) {
+						if !svc.runsIn(labels["cluster"]) {
 							return
 						}
-						generator(
-							ctx,
-							log.NewAppLogger(
-								labels,
-								log.NewOtelLogger(string(serviceName), labels),
-							),
-							metadata,
-						)
-					} else {
-						generator(ctx, log.NewAppLogger(labels, logger), metadata)
-					}
-				},
-			)
+						labels = svc.streamLabels(labels)
+						stream := &Stream{
+							Metadata:   svc.streamMetadata(metadata),
+							Rand:       log.Fork(f),
+							Clocks:     clocks,
+							Running:    &running,
+							Incidents:  incidents.For(labels),
+							SplitLines: svc.SplitLines,
+						}
+						if svc.Otel {
+							if !*useOtel {
+								return
+							}
+							client, err := otelPool.NewLogger(svc.Name, labels, metadata, otlpConfig)
+							if err != nil {
+								panic(err)
+							}
+							otelLogger := metrics.Instrument("otel", client)
+							if backfill != nil {
+								otelLogger = backfill.Logger(otelLogger)
+							}
+							stream.Logger = log.NewAppLogger(labels, otelLogger)
+						} else {
+							stream.Logger = log.NewAppLogger(labels, logger)
+						}
+						streams = append(streams, stream)
+					},
+				)
+				metrics.SetActiveStreams(namespace.Name, svc.Name, len(streams))
+				// The service rate is split across all of its streams, whatever their pod count.
+				for _, stream := range streams {
+					stream.Rate = svc.streamRate(multiplier, len(streams))
+					generator(ctx, stream)
+				}
+			}
+		}
+	}
+
+	if backfill != nil {
+		backfill.Start()
+		if !*live {
+			go func() {
+				<-backfill.Done()
+				fmt.Fprintln(os.Stderr, "Backfill done")
+				stop()
+			}()
 		}
 	}
-	startFailingMimirPod(ctx, logger)
 
 	<-ctx.Done()
+	stop()
+
+	fmt.Fprintf(os.Stderr, "Shutting down, flushing logs within %s\n", *shutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), *shutdownTimeout)
+	defer cancel()
+	if err := shutdown(shutdownCtx, &running, backfill, sinks); err != nil {
+		fmt.Fprintf(os.Stderr, "Shutdown: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+// shutdown waits for the generators to write their last line, then closes all sinks. Generators get half of the
+// time left before the deadline of ctx, so that the sinks are still closed and flushed when one of them is stuck.
+func shutdown(ctx context.Context, running *sync.WaitGroup, backfill *Backfill, sinks []log.Closer) error {
+	stopped := make(chan struct{})
+	go func() {
+		running.Wait()
+		close(stopped)
+	}()
+	wait := ctx
+	if deadline, ok := ctx.Deadline(); ok {
+		var cancel context.CancelFunc
+		wait, cancel = context.WithTimeout(ctx, time.Until(deadline)/2)
+		defer cancel()
+	}
+	var errs []error
+	select {
+	case <-stopped:
+	case <-wait.Done():
+		errs = append(errs, fmt.Errorf("generators did not stop: %w", wait.Err()))
+	}
+
+	if backfill != nil {
+		backfill.Flush()
+	}
+
+	closeErrs := make([]error, len(sinks))
+	var wg sync.WaitGroup
+	for i, sink := range sinks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			closeErrs[i] = sink.Close(ctx)
+		}()
+	}
+	wg.Wait()
+	return errors.Join(append(errs, closeErrs...)...)
 }
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafana/explore-logs/generator/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sink records whether it was closed before the deadline.
+type sink struct {
+	closed, inTime bool
+}
+
+func (s *sink) Close(ctx context.Context) error {
+	s.closed, s.inTime = true, ctx.Err() == nil
+	return nil
+}
+
+func TestShutdown(t *testing.T) {
+	var running sync.WaitGroup
+	running.Add(1)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		running.Done()
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	first, second := &sink{}, &sink{}
+
+	require.NoError(t, shutdown(ctx, &running, nil, []log.Closer{first, second}))
+	assert.True(t, first.closed && first.inTime)
+	assert.True(t, second.closed && second.inTime)
+}
+
+// closeSignal reports the error of the context a sink is closed with.
+type closeSignal chan error
+
+func (s closeSignal) Close(ctx context.Context) error {
+	s <- ctx.Err()
+	return nil
+}
+
+// deadlineContext has a deadline but is never done, the sinks can take their time.
+type deadlineContext struct {
+	context.Context
+	deadline time.Time
+}
+
+func (c deadlineContext) Deadline() (time.Time, bool) {
+	return c.deadline, true
+}
+
+func TestShutdownStuckGenerator(t *testing.T) {
+	var running sync.WaitGroup
+	running.Add(1)
+	defer running.Done()
+	ctx := deadlineContext{Context: context.Background(), deadline: time.Now().Add(20 * time.Millisecond)}
+	closed := make(closeSignal, 1)
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- shutdown(ctx, &running, nil, []log.Closer{closed})
+	}()
+	select {
+	case err := <-closed:
+		assert.NoError(t, err, "sinks are closed with the rest of the deadline")
+	case <-time.After(time.Minute):
+		t.Fatal("sinks are not closed while a generator is stuck")
+	}
+	require.ErrorContains(t, <-errs, "generators did not stop")
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+
+	"github.com/grafana/explore-logs/generator/log"
+	"github.com/grafana/loki/pkg/push"
+	"github.com/prometheus/common/model"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed scenarios/default.yaml
+var defaultScenario []byte
+
+// defaultRate is the lines per second of a service without a rate.
+const defaultRate = 10
+
+// Scenario describes the namespaces and services the generator produces logs for.
+type Scenario struct {
+	Namespaces []NamespaceConfig `yaml:"namespaces"`
+	// Incidents are applied on top of the namespaces, see IncidentConfig.
+	Incidents []IncidentConfig `yaml:"incidents"`
+}
+
+// NamespaceConfig groups the services of a namespace.
+type NamespaceConfig struct {
+	Name     string          `yaml:"name"`
+	Services []ServiceConfig `yaml:"services"`
+}
+
+// ServiceConfig describes the streams of a single service.
+type ServiceConfig struct {
+	Name string `yaml:"name"`
+	// Format selects the LogGenerator producing the lines, see formats.
+	Format string `yaml:"format"`
+	// Pods is the number of pods per cluster, a random count between 1 and 10 when unset.
+	Pods int `yaml:"pods"`
+	// Clusters restricts the service to some of log.Clusters, it runs in all of them when unset.
+	Clusters []string `yaml:"clusters"`
+	// MinimalLabels only sets the cluster, namespace and service_name stream labels, without env, file and
+	// __stream_shard__.
+	MinimalLabels bool `yaml:"minimal_labels"`
+	// Levels weights the level of each line, log.DefaultLevelMix when unset.
+	Levels map[string]int `yaml:"levels"`
+	// Rate is the target entries per second of the service, split evenly across its pod streams. defaultRate when unset.
+	// With SplitLines, every line of a multi-line entry counts.
+	Rate float64 `yaml:"rate"`
+	// Metadata is added as structured metadata to every line.
+	Metadata map[string]string `yaml:"metadata"`
+	// NoMetadata drops the generated traceID, pod and user structured metadata.
+	NoMetadata bool `yaml:"no_metadata"`
+	// Otel ships the service through the OpenTelemetry logger instead of the configured sink.
+	Otel bool `yaml:"otel"`
+	// SplitLines writes every line of multi-line entries, such as stack traces, as an entry of its own, like an
+	// agent without multi-line handling.
+	SplitLines bool `yaml:"split_lines"`
+	// Templates are the lines of a service with the template format, see LineTemplate.
+	Templates []LineTemplate `yaml:"templates"`
+}
+
+// LoadScenario reads a YAML or JSON scenario file, or the built-in default scenario when path is empty.
+func LoadScenario(path string) (*Scenario, error) {
+	data := defaultScenario
+	if path != "" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("failed to read scenario: %w", err)
+		}
+	}
+
+	var scenario Scenario
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&scenario); err != nil {
+		return nil, fmt.Errorf("failed to parse scenario %q: %w", path, err)
+	}
+	if err := scenario.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid scenario %q: %w", path, err)
+	}
+	return &scenario, nil
+}
+
+// LoadIncidents adds the incidents of a YAML or JSON file to the scenario.
+func (s *Scenario) LoadIncidents(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read incidents: %w", err)
+	}
+
+	var file struct {
+		Incidents []IncidentConfig `yaml:"incidents"`
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&file); err != nil {
+		return fmt.Errorf("failed to parse incidents %q: %w", path, err)
+	}
+	s.Incidents = append(s.Incidents, file.Incidents...)
+	if err := s.Validate(); err != nil {
+		return fmt.Errorf("invalid incidents %q: %w", path, err)
+	}
+	return nil
+}
+
+// Validate checks that every service can be turned into a LogGenerator, and that incidents target existing streams.
+func (s *Scenario) Validate() error {
+	if len(s.Namespaces) == 0 {
+		return errors.New("no namespaces defined")
+	}
+	for _, ns := range s.Namespaces {
+		if ns.Name == "" {
+			return errors.New("namespace without a name")
+		}
+		for _, svc := range ns.Services {
+			if svc.Name == "" {
+				return fmt.Errorf("namespace %s: service without a name", ns.Name)
+			}
+			if _, ok := formats[svc.Format]; !ok {
+				return fmt.Errorf("service %s/%s: unknown format %q", ns.Name, svc.Name, svc.Format)
+			}
+			if svc.Pods < 0 {
+				return fmt.Errorf("service %s/%s: pods can not be negative", ns.Name, svc.Name)
+			}
+			if svc.Rate < 0 {
+				return fmt.Errorf("service %s/%s: rate can not be negative", ns.Name, svc.Name)
+			}
+			for _, cluster := range svc.Clusters {
+				if !slices.Contains(log.Clusters, cluster) {
+					return fmt.Errorf("service %s/%s: unknown cluster %q", ns.Name, svc.Name, cluster)
+				}
+			}
+			if (svc.Format == "template") != (len(svc.Templates) > 0) {
+				return fmt.Errorf("service %s/%s: templates are required by, and only allowed with, the template format", ns.Name, svc.Name)
+			}
+			if _, _, err := compileTemplates(svc.Templates); err != nil {
+				return fmt.Errorf("service %s/%s: %w", ns.Name, svc.Name, err)
+			}
+			for level, weight := range svc.Levels {
+				if !slices.Contains(log.Levels, model.LabelValue(level)) {
+					return fmt.Errorf("service %s/%s: unknown level %q", ns.Name, svc.Name, level)
+				}
+				if weight < 0 {
+					return fmt.Errorf("service %s/%s: level %s weight can not be negative", ns.Name, svc.Name, level)
+				}
+			}
+		}
+	}
+	for _, incident := range s.Incidents {
+		if incident.Name == "" {
+			return errors.New("incident without a name")
+		}
+		if err := incident.validate(); err != nil {
+			return fmt.Errorf("incident %s: %w", incident.Name, err)
+		}
+		if !slices.ContainsFunc(s.Namespaces, func(ns NamespaceConfig) bool {
+			return matches(incident.Namespace, model.LabelValue(ns.Name)) && slices.ContainsFunc(ns.Services, func(svc ServiceConfig) bool {
+				return matches(incident.Service, model.LabelValue(svc.Name))
+			})
+		}) {
+			return fmt.Errorf("incident %s: no service matches namespace %q and service %q", incident.Name, incident.Namespace, incident.Service)
+		}
+		if incident.Cluster != "" && !slices.Contains(log.Clusters, incident.Cluster) {
+			return fmt.Errorf("incident %s: unknown cluster %q", incident.Name, incident.Cluster)
+		}
+	}
+	return nil
+}
+
+// LevelMix returns the level distribution of the service.
+func (svc ServiceConfig) LevelMix() log.LevelMix {
+	if len(svc.Levels) == 0 {
+		return log.DefaultLevelMix
+	}
+	mix := make(log.LevelMix, 0, len(svc.Levels))
+	for _, level := range log.Levels {
+		if weight, ok := svc.Levels[string(level)]; ok {
+			mix = append(mix, log.LevelWeight{Level: level, Weight: weight})
+		}
+	}
+	return mix
+}
+
+// streamRate returns the lines per second of each of the service's streams.
+func (svc ServiceConfig) streamRate(multiplier float64, streams int) float64 {
+	rate := svc.Rate
+	if rate == 0 {
+		rate = defaultRate
+	}
+	return rate * multiplier / float64(streams)
+}
+
+// runsIn tells whether the service has streams in cluster.
+func (svc ServiceConfig) runsIn(cluster model.LabelValue) bool {
+	return len(svc.Clusters) == 0 || slices.Contains(svc.Clusters, string(cluster))
+}
+
+// streamLabels returns the labels of a pod stream.
+func (svc ServiceConfig) streamLabels(generated model.LabelSet) model.LabelSet {
+	if !svc.MinimalLabels {
+		return generated
+	}
+	labels := model.LabelSet{}
+	for _, name := range []model.LabelName{"cluster", "namespace", "service_name"} {
+		labels[name] = generated[name]
+	}
+	return labels
+}
+
+// streamMetadata returns the structured metadata of a pod stream.
+func (svc ServiceConfig) streamMetadata(generated push.LabelsAdapter) push.LabelsAdapter {
+	metadata := push.LabelsAdapter{}
+	if !svc.NoMetadata {
+		metadata = append(metadata, generated...)
+	}
+	names := make([]string, 0, len(svc.Metadata))
+	for name := range svc.Metadata {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		metadata = append(metadata, push.LabelAdapter{Name: name, Value: svc.Metadata[name]})
+	}
+	return metadata
+}
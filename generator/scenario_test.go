@@ -0,0 +1,83 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/grafana/explore-logs/generator/log"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadDefaultScenario(t *testing.T) {
+	scenario, err := LoadScenario("")
+	require.NoError(t, err)
+
+	services := map[string]ServiceConfig{}
+	for _, ns := range scenario.Namespaces {
+		for _, svc := range ns.Services {
+			services[ns.Name+"/"+svc.Name] = svc
+		}
+	}
+	assert.Equal(t, "common_log", services["gateway/nginx"].Format)
+	assert.True(t, services["gateway/nginx"].NoMetadata)
+	assert.Equal(t, 8, services["tempo-prod/tempo-ingester"].Pods)
+	assert.True(t, services["e-commerce/shopping-cart-otel"].Otel)
+
+	// The failing mimir-ingester is a single stream, labeled like the pod it replaced.
+	mimir := services["mimir/mimir-ingester"]
+	assert.True(t, mimir.runsIn("us-west-1"))
+	assert.False(t, mimir.runsIn("us-east-1"))
+	assert.True(t, services["gateway/nginx"].runsIn("us-east-1"))
+	labels := model.LabelSet{"cluster": "us-west-1", "namespace": "mimir", "service_name": "mimir-ingester", "env": "prod", "file": "mimir.txt", "__stream_shard__": "1"}
+	assert.Equal(t, model.LabelSet{"cluster": "us-west-1", "namespace": "mimir", "service_name": "mimir-ingester"}, mimir.streamLabels(labels))
+	assert.Equal(t, labels, services["gateway/nginx"].streamLabels(labels))
+}
+
+func TestLoadScenarioFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scenario.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"namespaces": [{
+			"name": "payments",
+			"services": [{
+				"name": "checkout",
+				"format": "json",
+				"pods": 2,
+				"levels": {"info": 9, "error": 1},
+				"rate": 20,
+				"metadata": {"team": "payments", "region": "eu"}
+			}]
+		}]
+	}`), 0o644))
+
+	scenario, err := LoadScenario(path)
+	require.NoError(t, err)
+	svc := scenario.Namespaces[0].Services[0]
+	assert.Equal(t, log.LevelMix{{Level: log.INFO, Weight: 9}, {Level: log.ERROR, Weight: 1}}, svc.LevelMix())
+	assert.Equal(t, 5.0, svc.streamRate(2, 8))
+
+	metadata := svc.streamMetadata(nil)
+	require.Len(t, metadata, 2)
+	assert.Equal(t, "region", metadata[0].Name)
+	assert.Equal(t, "team", metadata[1].Name)
+}
+
+func TestLoadScenarioInvalid(t *testing.T) {
+	for name, content := range map[string]string{
+		"unknown format":  "namespaces: [{name: a, services: [{name: b, format: nope}]}]",
+		"unknown level":   "namespaces: [{name: a, services: [{name: b, format: json, levels: {fatal: 1}}]}]",
+		"unknown field":   "namespaces: [{name: a, services: [{name: b, format: json, podz: 1}]}]",
+		"no namespaces":   "namespaces: []",
+		"negative rate":   "namespaces: [{name: a, services: [{name: b, format: json, rate: -1}]}]",
+		"unknown cluster": "namespaces: [{name: a, services: [{name: b, format: json, clusters: [mars-1]}]}]",
+	} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "scenario.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+			_, err := LoadScenario(path)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/grafana/explore-logs/generator/flog"
+	"github.com/grafana/explore-logs/generator/log"
+	"github.com/prometheus/common/model"
+)
+
+// LineTemplate is a line of a templated service. Line holds placeholders such as {{ts}}, {{seq 5}} or
+// {{choice GET POST}} replaced with random values on every line, see placeholders.
+type LineTemplate struct {
+	Line string `yaml:"line"`
+	// Level is the level of the line, info when unset.
+	Level string `yaml:"level"`
+	// Weight is the relative frequency of the line among the templates without an interval, 1 when unset.
+	Weight int `yaml:"weight"`
+	// Interval writes the line once per interval and pod instead of at the service's rate, like a periodic poll.
+	Interval time.Duration `yaml:"interval"`
+}
+
+// render writes a value of a line, drawn from f at t.
+type render func(f *gofakeit.Faker, t time.Time) string
+
+// placeholders maps the name of a placeholder to the function compiling its arguments.
+var placeholders = map[string]func(args []string) (render, error){
+	"ts":       noArgs(func(_ *gofakeit.Faker, t time.Time) string { return t.Format(time.RFC3339Nano) }),
+	"orgID":    noArgs(func(f *gofakeit.Faker, _ time.Time) string { return log.RandOrgID(f) }),
+	"userID":   noArgs(func(f *gofakeit.Faker, _ time.Time) string { return log.RandUserID(f) }),
+	"duration": noArgs(func(f *gofakeit.Faker, _ time.Time) string { return log.RandDuration(f) }),
+	"ip":       noArgs(func(f *gofakeit.Faker, _ time.Time) string { return flog.FakeIP(f) }),
+	"uri":      noArgs(func(f *gofakeit.Faker, _ time.Time) string { return log.RandURI(f) }),
+	"file":     noArgs(func(f *gofakeit.Faker, _ time.Time) string { return log.RandFileName(f) }),
+	"error":    noArgs(func(f *gofakeit.Faker, _ time.Time) string { return log.RandError(f) }),
+	"uuid":     noArgs(func(f *gofakeit.Faker, _ time.Time) string { return f.UUID() }),
+	// {{seq n}} is n random lowercase letters and digits.
+	"seq": func(args []string) (render, error) {
+		n, err := intArgs(args, 1, 1)
+		if err != nil {
+			return nil, err
+		}
+		return func(f *gofakeit.Faker, _ time.Time) string { return log.RandSeq(f, n[0]) }, nil
+	},
+	// {{int max}} is between 0 and max excluded, {{int min max}} between min and max included.
+	"int": func(args []string) (render, error) {
+		n, err := intArgs(args, 1, 2)
+		if err != nil {
+			return nil, err
+		}
+		if len(n) == 1 {
+			if n[0] <= 0 {
+				return nil, errors.New("max must be positive")
+			}
+			return func(f *gofakeit.Faker, _ time.Time) string { return strconv.Itoa(f.IntN(n[0])) }, nil
+		}
+		if n[0] > n[1] {
+			return nil, errors.New("min is greater than max")
+		}
+		return func(f *gofakeit.Faker, _ time.Time) string { return strconv.Itoa(f.Number(n[0], n[1])) }, nil
+	},
+	// {{choice a b "c d"}} is one of its arguments.
+	"choice": func(args []string) (render, error) {
+		if len(args) == 0 {
+			return nil, errors.New("nothing to choose from")
+		}
+		return func(f *gofakeit.Faker, _ time.Time) string { return args[f.IntN(len(args))] }, nil
+	},
+}
+
+func noArgs(r render) func(args []string) (render, error) {
+	return func(args []string) (render, error) {
+		if len(args) > 0 {
+			return nil, errors.New("takes no arguments")
+		}
+		return r, nil
+	}
+}
+
+func intArgs(args []string, min, max int) ([]int, error) {
+	if len(args) < min || len(args) > max {
+		return nil, fmt.Errorf("takes %d to %d integer arguments", min, max)
+	}
+	n := make([]int, len(args))
+	for i, arg := range args {
+		var err error
+		if n[i], err = strconv.Atoi(arg); err != nil {
+			return nil, fmt.Errorf("%q is not an integer", arg)
+		}
+	}
+	return n, nil
+}
+
+// compileLine turns a line with placeholders into the function rendering it. {{level}} is replaced with level.
+func compileLine(line string, level model.LabelValue) (render, error) {
+	var parts []render
+	for rest := line; rest != ""; {
+		start := strings.Index(rest, "{{")
+		if start < 0 {
+			parts = append(parts, literal(rest))
+			break
+		}
+		end := strings.Index(rest[start:], "}}")
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated placeholder %q", rest[start:])
+		}
+		if start > 0 {
+			parts = append(parts, literal(rest[:start]))
+		}
+		placeholder := rest[start+2 : start+end]
+		rest = rest[start+end+2:]
+
+		args, err := splitArgs(placeholder)
+		if err != nil {
+			return nil, fmt.Errorf("placeholder {{%s}}: %w", placeholder, err)
+		}
+		if len(args) == 0 {
+			return nil, errors.New("empty placeholder")
+		}
+		if args[0] == "level" && len(args) == 1 {
+			parts = append(parts, literal(level))
+			continue
+		}
+		compile, ok := placeholders[args[0]]
+		if !ok {
+			return nil, fmt.Errorf("unknown placeholder {{%s}}", placeholder)
+		}
+		part, err := compile(args[1:])
+		if err != nil {
+			return nil, fmt.Errorf("placeholder {{%s}}: %w", placeholder, err)
+		}
+		parts = append(parts, part)
+	}
+	return func(f *gofakeit.Faker, t time.Time) string {
+		var b strings.Builder
+		for _, part := range parts {
+			b.WriteString(part(f, t))
+		}
+		return b.String()
+	}, nil
+}
+
+func literal[S ~string](s S) render {
+	return func(*gofakeit.Faker, time.Time) string { return string(s) }
+}
+
+// splitArgs splits a placeholder on spaces, double-quoted arguments may hold spaces.
+func splitArgs(s string) ([]string, error) {
+	var args []string
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimSpace(s) {
+		if s[0] == '"' {
+			quoted, err := strconv.QuotedPrefix(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid quoted argument %s", s)
+			}
+			arg, _ := strconv.Unquote(quoted)
+			args = append(args, arg)
+			s = s[len(quoted):]
+			continue
+		}
+		arg, rest, _ := strings.Cut(s, " ")
+		args = append(args, arg)
+		s = rest
+	}
+	return args, nil
+}
+
+// periodicLine is a line written once per interval.
+type periodicLine struct {
+	interval time.Duration
+	level    model.LabelValue
+	line     render
+}
+
+// compileTemplates splits templates into the lines written at the service's rate and the periodic ones.
+func compileTemplates(templates []LineTemplate) ([]weightedLine, []periodicLine, error) {
+	var lines []weightedLine
+	var periodic []periodicLine
+	for i, tmpl := range templates {
+		level := log.INFO
+		if tmpl.Level != "" {
+			level = model.LabelValue(tmpl.Level)
+		}
+		switch {
+		case !slices.Contains(log.Levels, level):
+			return nil, nil, fmt.Errorf("template %d: unknown level %q", i, tmpl.Level)
+		case tmpl.Weight < 0:
+			return nil, nil, fmt.Errorf("template %d: weight can not be negative", i)
+		case tmpl.Interval < 0:
+			return nil, nil, fmt.Errorf("template %d: interval can not be negative", i)
+		}
+		line, err := compileLine(tmpl.Line, level)
+		if err != nil {
+			return nil, nil, fmt.Errorf("template %d: %w", i, err)
+		}
+		if tmpl.Interval > 0 {
+			periodic = append(periodic, periodicLine{tmpl.Interval, level, line})
+			continue
+		}
+		weight := tmpl.Weight
+		if weight == 0 {
+			weight = 1
+		}
+		lines = append(lines, weightedLine{weight, level, line})
+	}
+	return lines, periodic, nil
+}
+
+// mustCompileTemplates compiles built-in templates.
+func mustCompileTemplates(templates []LineTemplate) []weightedLine {
+	lines, _, err := compileTemplates(templates)
+	if err != nil {
+		panic(err)
+	}
+	return lines
+}
+
+// templateFormat builds a LogGenerator writing templates. With levels, the service's level mix draws the level of
+// each line before one of its templates is picked by weight, the weights alone pick the line otherwise.
+func templateFormat(templates []LineTemplate) func(svc ServiceConfig) LogGenerator {
+	return func(svc ServiceConfig) LogGenerator {
+		if templates == nil {
+			return templateFormat(svc.Templates)(svc)
+		}
+		// Scenario templates were compiled by Scenario.Validate already.
+		lines, periodic, err := compileTemplates(templates)
+		if err != nil {
+			panic(err)
+		}
+		levels := svc.LevelMix()
+		return func(ctx context.Context, stream *Stream) {
+			if len(lines) > 0 {
+				stream.Go(func(f *gofakeit.Faker, clock Clock) {
+					for ctx.Err() == nil {
+						t := clock.Now()
+						var l weightedLine
+						if len(svc.Levels) > 0 {
+							l = pickLevelLine(f, stream.Incidents.level(f, t, levels), lines)
+						} else {
+							l = stream.Incidents.pickLine(f, t, lines)
+						}
+						entries := stream.log(f, l.level, t, l.line(f, t), stream.Metadata)
+						clock.Sleep(ctx, stream.pause(f, t, entries))
+					}
+				})
+			}
+			for _, p := range periodic {
+				stream.Go(func(f *gofakeit.Faker, clock Clock) {
+					// Spread the pods over the interval rather than writing all their lines at once.
+					clock.Sleep(ctx, time.Duration(f.Float64()*float64(p.interval)))
+					for ctx.Err() == nil {
+						t := clock.Now()
+						stream.log(f, p.level, t, p.line(f, t), stream.Metadata)
+						clock.Sleep(ctx, stream.Incidents.pause(t, p.interval))
+					}
+				})
+			}
+		}
+	}
+}
+
+// pickLevelLine picks one of the lines at level, or of all lines when none is.
+func pickLevelLine(f *gofakeit.Faker, level model.LabelValue, lines []weightedLine) weightedLine {
+	var matching []weightedLine
+	for _, l := range lines {
+		if l.level == level {
+			matching = append(matching, l)
+		}
+	}
+	if len(matching) == 0 {
+		return pickLine(f, lines)
+	}
+	return pickLine(f, matching)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/grafana/explore-logs/generator/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompileLine(t *testing.T) {
+	line, err := compileLine(`ts={{ts}} level={{level}} tenant={{orgID}} pod=ingester-{{seq 5}} n={{int 3 5}} method={{ choice GET "POST /a" }} took={{duration}} from={{ip}}`, log.WARN)
+	require.NoError(t, err)
+
+	ts := time.Date(2024, 3, 5, 7, 8, 9, 1000, time.UTC)
+	f := gofakeit.New(1)
+	for i := 0; i < 20; i++ {
+		assert.Regexp(t, regexp.MustCompile(`^ts=2024-03-05T07:08:09.000001Z level=warn tenant=(1218|29|1010|2419|2919) pod=ingester-[a-z0-9]{5} n=[345] method=(GET|POST /a) took=\S+ from=\S+$`), line(f, ts))
+	}
+
+	line, err = compileLine("no placeholders", log.INFO)
+	require.NoError(t, err)
+	assert.Equal(t, "no placeholders", line(f, ts))
+}
+
+func TestCompileLineInvalid(t *testing.T) {
+	for _, line := range []string{
+		"ts={{ts",
+		"{{}}",
+		"{{nope}}",
+		"{{ts now}}",
+		"{{seq}}",
+		"{{seq five}}",
+		"{{int 0}}",
+		"{{int 5 3}}",
+		"{{choice}}",
+		`{{choice "open}}`,
+	} {
+		_, err := compileLine(line, log.INFO)
+		assert.Error(t, err, line)
+	}
+}
+
+func TestCompileTemplates(t *testing.T) {
+	lines, periodic, err := compileTemplates([]LineTemplate{
+		{Line: "a", Weight: 3},
+		{Line: "b", Level: "error"},
+		{Line: "poll", Interval: time.Minute},
+	})
+	require.NoError(t, err)
+	require.Len(t, lines, 2)
+	assert.Equal(t, 3, lines[0].weight)
+	assert.Equal(t, log.INFO, lines[0].level)
+	assert.Equal(t, 1, lines[1].weight)
+	assert.Equal(t, log.ERROR, lines[1].level)
+	require.Len(t, periodic, 1)
+	assert.Equal(t, time.Minute, periodic[0].interval)
+
+	f := gofakeit.New(1)
+	assert.Equal(t, log.ERROR, pickLevelLine(f, log.ERROR, lines).level)
+	assert.Contains(t, []string{"a", "b"}, pickLevelLine(f, log.DEBUG, lines).line(f, time.Now()), "any line when no template has the level")
+
+	for _, tmpl := range []LineTemplate{
+		{Line: "a", Level: "fatal"},
+		{Line: "a", Weight: -1},
+		{Line: "a", Interval: -time.Second},
+		{Line: "{{nope}}"},
+	} {
+		_, _, err := compileTemplates([]LineTemplate{tmpl})
+		assert.Error(t, err, tmpl)
+	}
+}
+
+func TestLoadTemplatesScenario(t *testing.T) {
+	scenario, err := LoadScenario("scenarios/templates-example.yaml")
+	require.NoError(t, err)
+	svc := scenario.Namespaces[0].Services[0]
+	require.Len(t, svc.Templates, 4)
+	assert.Equal(t, time.Minute, svc.Templates[3].Interval)
+
+	scenario.Namespaces[0].Services[0].Format = "json"
+	assert.Error(t, scenario.Validate(), "templates require the template format")
+	scenario.Namespaces[0].Services[0] = ServiceConfig{Name: "empty", Format: "template"}
+	assert.Error(t, scenario.Validate(), "the template format requires templates")
+}
+
+func TestBuiltinTemplates(t *testing.T) {
+	mustCompileTemplates(mimirTemplates)
+	mustCompileTemplates(tempoTemplates)
+	for _, templates := range lokiOtelTemplates {
+		mustCompileTemplates(templates)
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/grafana/explore-logs/generator/log"
+	"gopkg.in/yaml.v3"
+)
+
+// TenantConfig describes a tenant the generator pushes to, with its own data shape.
+type TenantConfig struct {
+	ID string `yaml:"id"`
+	// URL is the Loki push URL of the tenant, -url when unset.
+	URL string `yaml:"url"`
+	// Username and Password are the basic auth credentials, Username defaults to ID. No auth without a password.
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	// Scenario and Incidents are the files describing the tenant's logs, -config and -incidents when unset.
+	Scenario  string `yaml:"scenario"`
+	Incidents string `yaml:"incidents"`
+	// Namespaces restricts the scenario to a subset of its namespaces, all of them when unset.
+	Namespaces []string `yaml:"namespaces"`
+	// Encoding and Gzip override -push-encoding and -push-gzip, to mimic the agent of the tenant.
+	Encoding string `yaml:"encoding"`
+	Gzip     bool   `yaml:"gzip"`
+	// RateMultiplier scales the rates of the tenant's services on top of -rate-multiplier, 1 when unset.
+	RateMultiplier float64 `yaml:"rate_multiplier"`
+}
+
+// LoadTenants reads a YAML or JSON tenants file. Environment variables such as ${TENANT_TOKEN} are expanded, so
+// that credentials do not have to be written in the file.
+func LoadTenants(path string) ([]TenantConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tenants: %w", err)
+	}
+
+	var file struct {
+		Tenants []TenantConfig `yaml:"tenants"`
+	}
+	dec := yaml.NewDecoder(bytes.NewReader([]byte(os.ExpandEnv(string(data)))))
+	dec.KnownFields(true)
+	if err := dec.Decode(&file); err != nil {
+		return nil, fmt.Errorf("failed to parse tenants %q: %w", path, err)
+	}
+	if len(file.Tenants) == 0 {
+		return nil, fmt.Errorf("no tenants defined in %q", path)
+	}
+	for _, tenant := range file.Tenants {
+		if tenant.ID == "" {
+			return nil, fmt.Errorf("tenant without an id in %q", path)
+		}
+		if tenant.RateMultiplier < 0 {
+			return nil, fmt.Errorf("tenant %s: rate_multiplier can not be negative", tenant.ID)
+		}
+	}
+	return file.Tenants, nil
+}
+
+// LoadScenario loads the scenario of the tenant, with its incidents, restricted to its namespaces.
+func (t TenantConfig) LoadScenario() (*Scenario, error) {
+	scenario, err := LoadScenario(t.Scenario)
+	if err != nil {
+		return nil, err
+	}
+	if t.Incidents != "" {
+		if err := scenario.LoadIncidents(t.Incidents); err != nil {
+			return nil, err
+		}
+	}
+	if len(t.Namespaces) > 0 {
+		if scenario, err = scenario.Subset(t.Namespaces); err != nil {
+			return nil, fmt.Errorf("tenant %s: %w", t.ID, err)
+		}
+	}
+	return scenario, nil
+}
+
+// Subset returns the scenario restricted to namespaces, with the incidents still targeting one of them.
+func (s *Scenario) Subset(namespaces []string) (*Scenario, error) {
+	subset := &Scenario{}
+	for _, name := range namespaces {
+		i := slices.IndexFunc(s.Namespaces, func(ns NamespaceConfig) bool { return ns.Name == name })
+		if i < 0 {
+			return nil, fmt.Errorf("unknown namespace %q", name)
+		}
+		subset.Namespaces = append(subset.Namespaces, s.Namespaces[i])
+	}
+	for _, incident := range s.Incidents {
+		if incident.Namespace == "" || slices.Contains(namespaces, incident.Namespace) {
+			subset.Incidents = append(subset.Incidents, incident)
+		}
+	}
+	if err := subset.Validate(); err != nil {
+		return nil, err
+	}
+	return subset, nil
+}
+
+// NewLokiLogger creates a logger pushing to the tenant, with the batching and retries of base.
+func (t TenantConfig) NewLokiLogger(base log.LokiConfig) (*log.LokiLogger, error) {
+	cfg := base
+	cfg.URL = t.URL
+	cfg.TenantID = t.ID
+	if t.Password != "" {
+		cfg.Username = t.Username
+		if cfg.Username == "" {
+			cfg.Username = t.ID
+		}
+		cfg.Password = t.Password
+	}
+	if t.Encoding != "" {
+		cfg.Encoding = t.Encoding
+	}
+	if t.Gzip {
+		cfg.Gzip = true
+	}
+	return log.NewLokiLogger(cfg)
+}
+
+// OTLPConfig returns the OTLP export of the tenant's OTel services. Over HTTP, they are pushed to the OTLP endpoint
+// of the tenant's Loki unless base has an endpoint.
+func (t TenantConfig) OTLPConfig(base log.OTLPConfig) log.OTLPConfig {
+	cfg := base
+	if cfg.Protocol == "" || cfg.Protocol == log.OTLPGRPC {
+		// The collector holds the credentials.
+		return cfg
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = strings.TrimSuffix(t.URL, "/loki/api/v1/push") + "/otlp/v1/logs"
+	}
+	cfg.TenantID = t.ID
+	if t.Password != "" {
+		cfg.Username = t.Username
+		if cfg.Username == "" {
+			cfg.Username = t.ID
+		}
+		cfg.Password = t.Password
+	}
+	return cfg
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadTenants(t *testing.T) {
+	t.Setenv("TENANT_TOKEN", "s3cr3t")
+	path := filepath.Join(t.TempDir(), "tenants.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+tenants:
+  - id: "1"
+  - id: "2"
+    url: http://loki-2:3100/loki/api/v1/push
+    password: ${TENANT_TOKEN}
+    namespaces: [gateway]
+    rate_multiplier: 0.5
+`), 0o644))
+
+	tenants, err := LoadTenants(path)
+	require.NoError(t, err)
+	require.Len(t, tenants, 2)
+	assert.Equal(t, "s3cr3t", tenants[1].Password)
+	assert.Equal(t, 0.5, tenants[1].RateMultiplier)
+
+	scenario, err := tenants[1].LoadScenario()
+	require.NoError(t, err)
+	require.Len(t, scenario.Namespaces, 1)
+	assert.Equal(t, "gateway", scenario.Namespaces[0].Name)
+	assert.Empty(t, scenario.Incidents, "incidents of other namespaces are dropped")
+
+	_, err = TenantConfig{ID: "3", Namespaces: []string{"nope"}}.LoadScenario()
+	assert.Error(t, err)
+}
+
+func TestLoadTenantsExample(t *testing.T) {
+	tenants, err := LoadTenants("scenarios/tenants-example.yaml")
+	require.NoError(t, err)
+	for _, tenant := range tenants {
+		_, err := tenant.LoadScenario()
+		assert.NoError(t, err, tenant.ID)
+	}
+}